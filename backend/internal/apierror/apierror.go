@@ -0,0 +1,194 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Code identifies the kind of an API error. Codes are stable so clients can rely on them, unlike messages.
+type Code string
+
+const (
+	CodeInvalidRequest   Code = "invalid_request"   // The request is malformed
+	CodeValidationFailed Code = "validation_failed" // The request is well-formed but some of its fields are invalid
+	CodeUnauthenticated  Code = "unauthenticated"   // The request has no valid credentials
+	CodeForbidden        Code = "forbidden"         // The caller isn't allowed to perform the request
+	CodeOutOfScope       Code = "out_of_scope"      // The scan target is outside of the allowed scope
+	CodeNotFound         Code = "not_found"         // The resource doesn't exist
+	CodeConflict         Code = "conflict"          // The resource already exists
+	CodeRateLimited      Code = "rate_limited"      // The caller used up its request budget
+	CodeQueueFull        Code = "queue_full"        // Too many scans are already waiting for nmap
+	CodeScanFailed       Code = "scan_failed"       // nmap failed or its output couldn't be read
+	CodeInternal         Code = "internal_error"    // Anything unexpected
+	CodeTimeout          Code = "timeout"           // The request didn't complete in time
+)
+
+// statusCodes maps error codes to the HTTP status they are answered with
+var statusCodes = map[Code]int{
+	CodeInvalidRequest:   http.StatusBadRequest,
+	CodeValidationFailed: http.StatusUnprocessableEntity,
+	CodeUnauthenticated:  http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeOutOfScope:       http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeConflict:         http.StatusConflict,
+	CodeRateLimited:      http.StatusTooManyRequests,
+	CodeQueueFull:        http.StatusTooManyRequests,
+	CodeScanFailed:       http.StatusInternalServerError,
+	CodeInternal:         http.StatusInternalServerError,
+	CodeTimeout:          http.StatusGatewayTimeout,
+}
+
+// Error is an error returned by the API
+type Error struct {
+	Code    Code         `json:"code"`             // Stable error code
+	Message string       `json:"message"`          // Human readable message
+	Fields  []FieldError `json:"fields,omitempty"` // Invalid fields of the request, for validation errors
+	Err     error        `json:"-"`                // Underlying error, never sent to clients
+}
+
+// FieldError describes why a field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`           // Path of the field in the request body, e.g. "targets[0]"
+	Rule    string `json:"rule"`            // Validation rule the field broke, e.g. "required"
+	Param   string `json:"param,omitempty"` // Parameter of the rule, e.g. "1" for "min=1"
+	Message string `json:"message"`         // Human readable message
+}
+
+// New creates an API error
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an API error with the message of an underlying error
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+// Internal creates an internal error with a message that doesn't leak the details of the underlying error
+func Internal(message string, err error) *Error {
+	return &Error{Code: CodeInternal, Message: message, Err: err}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Err != nil && e.Err.Error() != e.Message {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status code the error is answered with
+func (e *Error) Status() int {
+	if status, ok := statusCodes[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// From returns the API error wrapped in err.
+// Timeouts are mapped to CodeTimeout and any other error to an internal error.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Code: CodeTimeout, Message: "Request timed out", Err: err}
+	}
+	return Internal("Internal server error", err)
+}
+
+// Validation maps the error of a validator to a validation error listing the invalid fields
+func Validation(err error) *Error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return Wrap(CodeInvalidRequest, err)
+	}
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fieldErr.Namespace()),
+			Rule:    fieldErr.Tag(),
+			Param:   fieldErr.Param(),
+			Message: fieldMessage(fieldErr),
+		})
+	}
+
+	return &Error{Code: CodeValidationFailed, Message: "Invalid request fields", Fields: fields, Err: err}
+}
+
+// Binding maps an error returned while binding the body or query of a request
+func Binding(err error) *Error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		message := "Expected a " + typeErr.Type.String() + " instead of a " + typeErr.Value
+		return &Error{
+			Code:    CodeInvalidRequest,
+			Message: "Invalid request body",
+			Fields:  []FieldError{{Field: typeErr.Field, Rule: "type", Param: typeErr.Type.String(), Message: message}},
+			Err:     err,
+		}
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return Validation(err)
+	}
+
+	return &Error{Code: CodeInvalidRequest, Message: "Invalid request: " + err.Error(), Err: err}
+}
+
+// Invalid creates a validation error for a single field
+func Invalid(field string, rule string, message string) *Error {
+	return &Error{
+		Code:    CodeValidationFailed,
+		Message: "Invalid request fields",
+		Fields:  []FieldError{{Field: field, Rule: rule, Message: message}},
+	}
+}
+
+// fieldPath strips the name of the top level struct from the namespace of a field
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// fieldMessage describes a broken validation rule
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "This field is required"
+	case "oneof":
+		return "Must be one of: " + fieldErr.Param()
+	case "min":
+		return "Must be at least " + fieldErr.Param()
+	case "max":
+		return "Must be at most " + fieldErr.Param()
+	case "unique":
+		return "Must not contain duplicates"
+	case "ip|fqdn":
+		return "Must be an IP address or hostname"
+	case "http_url":
+		return "Must be an http or https URL"
+	case "required_without":
+		return "Required unless " + fieldErr.Param() + " is set"
+	case "excluded_with":
+		return "Can't be set along with " + fieldErr.Param()
+	default:
+		return "Must be a valid " + fieldErr.Tag()
+	}
+}
@@ -0,0 +1,71 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	notFound := New(CodeNotFound, "host not found")
+	assert.Same(t, notFound, From(fmt.Errorf("querying host: %w", notFound)))
+	assert.Equal(t, http.StatusNotFound, From(notFound).Status())
+
+	timeout := From(fmt.Errorf("running nmap: %w", context.DeadlineExceeded))
+	assert.Equal(t, CodeTimeout, timeout.Code)
+	assert.Equal(t, http.StatusGatewayTimeout, timeout.Status())
+
+	internal := From(errors.New("connection refused"))
+	assert.Equal(t, CodeInternal, internal.Code)
+	assert.Equal(t, "Internal server error", internal.Message)
+	assert.Equal(t, http.StatusInternalServerError, internal.Status())
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	cause := errors.New("queue is full")
+	err := Wrap(CodeQueueFull, cause)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, http.StatusTooManyRequests, err.Status())
+}
+
+func TestValidation(t *testing.T) {
+	type request struct {
+		Targets []string `validate:"required,dive,ip"`
+		Profile string   `validate:"oneof=default quick"`
+	}
+
+	err := Validation(validator.New().Struct(request{Targets: []string{"10.0.0.1", "nope"}, Profile: "slow"}))
+	assert.Equal(t, CodeValidationFailed, err.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, err.Status())
+	assert.Equal(t, []FieldError{
+		{Field: "Targets[1]", Rule: "ip", Message: "Must be a valid ip"},
+		{Field: "Profile", Rule: "oneof", Param: "default quick", Message: "Must be one of: default quick"},
+	}, err.Fields)
+}
+
+func TestBinding(t *testing.T) {
+	var body struct {
+		Port int `json:"port"`
+	}
+
+	err := Binding(json.Unmarshal([]byte(`{"port": "22"}`), &body))
+	assert.Equal(t, CodeInvalidRequest, err.Code)
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+	assert.Equal(t, "port", err.Fields[0].Field)
+
+	err = Binding(json.Unmarshal([]byte(`{`), &body))
+	assert.Equal(t, CodeInvalidRequest, err.Code)
+	assert.Empty(t, err.Fields)
+}
+
+func TestErrorJSON(t *testing.T) {
+	body, err := json.Marshal(Internal("Unable to query schedules", errors.New("dial tcp: connection refused")))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"code": "internal_error", "message": "Unable to query schedules"}`, string(body))
+}
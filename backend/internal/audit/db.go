@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout MySQL uses for timestamp columns
+const timeLayout = "2006-01-02 15:04:05"
+
+// DefaultLimit is the number of entries returned when the filter sets no limit
+const DefaultLimit = 100
+
+// IDBClient is an interface that defines the methods for interacting with the AuditLog table.
+// The audit log is append-only, there are no methods to update or delete entries.
+type IDBClient interface {
+	InsertEntry(ctx context.Context, entry *Entry) error
+	QueryEntries(ctx context.Context, filter Filter) ([]*Entry, error)
+}
+
+// DBClient is a struct that implements the IDBClient interface.
+type DBClient struct {
+	DB     *sql.DB
+	Logger *zap.Logger
+}
+
+// NewDBClient creates a new instance of DBClient sharing an existing database connection.
+func NewDBClient(conn *sql.DB, logger *zap.Logger) *DBClient {
+	return &DBClient{
+		DB:     conn,
+		Logger: logger,
+	}
+}
+
+// InsertEntry appends an entry to the audit log and sets its ID.
+func (db *DBClient) InsertEntry(ctx context.Context, entry *Entry) error {
+	var workspaceID any
+	if entry.WorkspaceID != "" {
+		workspaceID = entry.WorkspaceID
+	}
+
+	queryString := `INSERT INTO AuditLog (workspace_id, actor, action, target, method, path, status_code, ip_address, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := db.DB.ExecContext(ctx, queryString, workspaceID, entry.Actor, entry.Action, entry.Target, entry.Method, entry.Path,
+		entry.StatusCode, entry.IPAddress, entry.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	entry.EntryID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// QueryEntries queries the audit log for entries matching the filter, newest first.
+func (db *DBClient) QueryEntries(ctx context.Context, filter Filter) ([]*Entry, error) {
+	queryString := `SELECT entry_id, workspace_id, actor, action, target, method, path, status_code, ip_address, timestamp FROM AuditLog WHERE 1 = 1`
+	var args []any
+	if filter.WorkspaceID != "" {
+		queryString += ` AND workspace_id = ?`
+		args = append(args, filter.WorkspaceID)
+	}
+	if filter.Actor != "" {
+		queryString += ` AND actor = ?`
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		queryString += ` AND action = ?`
+		args = append(args, filter.Action)
+	}
+	if filter.Target != "" {
+		queryString += ` AND target LIKE CONCAT('%', ?, '%')`
+		args = append(args, filter.Target)
+	}
+	if filter.IPAddress != "" {
+		queryString += ` AND ip_address = ?`
+		args = append(args, filter.IPAddress)
+	}
+	if !filter.Since.IsZero() {
+		queryString += ` AND timestamp >= ?`
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		queryString += ` AND timestamp < ?`
+		args = append(args, filter.Until.UTC())
+	}
+
+	limit := filter.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	queryString += ` ORDER BY entry_id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.DB.QueryContext(ctx, queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []*Entry{}
+	for rows.Next() {
+		var entry Entry
+		var workspaceID sql.NullString
+		var timestampStr string
+		err := rows.Scan(&entry.EntryID, &workspaceID, &entry.Actor, &entry.Action, &entry.Target, &entry.Method, &entry.Path,
+			&entry.StatusCode, &entry.IPAddress, &timestampStr)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.WorkspaceID = workspaceID.String
+		if entry.Timestamp, err = time.Parse(timeLayout, timestampStr); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,53 @@
+package audit
+
+import "time"
+
+// Actions recorded in the audit log
+const (
+	ActionScanStart        = "scan.start"
+	ActionHostTagsUpdate   = "host.tags.update"
+	ActionPolicyCreate     = "policy.create"
+	ActionPolicyDelete     = "policy.delete"
+	ActionWebhookCreate    = "webhook.create"
+	ActionWebhookDelete    = "webhook.delete"
+	ActionScheduleCreate   = "schedule.create"
+	ActionScheduleUpdate   = "schedule.update"
+	ActionScheduleDelete   = "schedule.delete"
+	ActionKeyCreate        = "key.create"
+	ActionKeyRevoke        = "key.revoke"
+	ActionWorkspaceCreate  = "workspace.create"
+	ActionMemberAdd        = "workspace.member.add"
+	ActionMemberRemove     = "workspace.member.remove"
+	ActionSubscriberCreate = "email.subscriber.create"
+	ActionSubscriberDelete = "email.subscriber.delete"
+	ActionHostsImport      = "hosts.import"
+	ActionScanImport       = "scan.import"
+	ActionAccessDenied     = "access.denied"
+)
+
+// Entry represents an action recorded in the audit log.
+// Entries are never updated or deleted once written.
+type Entry struct {
+	EntryID     string    `db:"entry_id" json:"entry_id"`
+	WorkspaceID string    `db:"workspace_id" json:"workspace_id,omitempty"` // Empty for actions outside of a workspace, e.g. key management
+	Actor       string    `db:"actor" json:"actor"`                         // Principal that performed the action
+	Action      string    `db:"action" json:"action"`
+	Target      string    `db:"target" json:"target,omitempty"` // Scanned targets or ID of the affected resource
+	Method      string    `db:"method" json:"method,omitempty"`
+	Path        string    `db:"path" json:"path,omitempty"`
+	StatusCode  int       `db:"status_code" json:"status_code,omitempty"`
+	IPAddress   string    `db:"ip_address" json:"ip_address,omitempty"` // Source IP of the request
+	Timestamp   time.Time `db:"timestamp" json:"timestamp"`
+}
+
+// Filter represents the filters that can be applied when querying the audit log
+type Filter struct {
+	WorkspaceID string    `form:"workspace_id" validate:"omitempty,numeric"`
+	Actor       string    `form:"actor" validate:"max=255"`
+	Action      string    `form:"action" validate:"max=255"`
+	Target      string    `form:"target" validate:"max=255"` // Only return entries whose target contains this value
+	IPAddress   string    `form:"ip_address" validate:"omitempty,ip"`
+	Since       time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until       time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit       int       `form:"limit" validate:"omitempty,min=1,max=1000"`
+}
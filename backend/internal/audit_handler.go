@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/audit"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) getAuditHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var filter audit.Filter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		s.Logger.Error("unable to bind query", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+
+	if err := validate.Struct(filter); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	entries, err := s.AuditDBClient.QueryEntries(ctx, filter)
+	if err != nil {
+		s.Logger.Error("unable to query audit log", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query audit log", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
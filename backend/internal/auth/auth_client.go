@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// keyPrefix starts every generated key so they are easy to spot in configs and leaks
+	keyPrefix = "nmp_"
+	// displayPrefixLength is the number of characters of a key stored in clear to tell keys apart
+	displayPrefixLength = 12
+)
+
+// ErrUnauthenticated is returned when a request carries no valid credentials.
+var ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+// AuthClient represents a client for creating and checking API keys
+type AuthClient struct {
+	Logger       *zap.Logger // Logger
+	DBClient     *DBClient   // Database client
+	adminKeyHash string      // Hash of the static admin key, used to bootstrap the first keys
+}
+
+// NewAuthClient creates a new AuthClient, adminKey is accepted as a key with the admin scope when not empty
+func NewAuthClient(logger *zap.Logger, DBClient *DBClient, adminKey string) *AuthClient {
+	client := &AuthClient{
+		Logger:   logger,
+		DBClient: DBClient,
+	}
+	if adminKey != "" {
+		client.adminKeyHash = HashKey(adminKey)
+	}
+	return client
+}
+
+// CreateKey generates a new API key, the plain key is returned once and only its hash is stored
+func (a *AuthClient) CreateKey(ctx context.Context, request CreateKeyRequest) (*CreateKeyResponse, error) {
+	plainKey, err := generateKey()
+	if err != nil {
+		a.Logger.Error("error generating api key", zap.Error(err))
+		return nil, fmt.Errorf("error generating api key")
+	}
+
+	key := &APIKey{
+		Name:   request.Name,
+		Prefix: plainKey[:displayPrefixLength],
+		Scopes: request.Scopes,
+	}
+
+	if err := a.DBClient.InsertKey(ctx, key, HashKey(plainKey)); err != nil {
+		a.Logger.Error("error inserting api key", zap.Error(err))
+		return nil, fmt.Errorf("error storing api key")
+	}
+
+	return &CreateKeyResponse{APIKey: key, Key: plainKey}, nil
+}
+
+// AuthenticateKey returns the principal of a plain API key, or ErrUnauthenticated
+func (a *AuthClient) AuthenticateKey(ctx context.Context, plainKey string) (*Principal, error) {
+	if plainKey == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	keyHash := HashKey(plainKey)
+	if a.adminKeyHash != "" && subtle.ConstantTimeCompare([]byte(keyHash), []byte(a.adminKeyHash)) == 1 {
+		return &Principal{ID: "admin", Name: "admin", Scopes: []string{ScopeAdmin}}, nil
+	}
+
+	key, err := a.DBClient.QueryKeyByHash(ctx, keyHash)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		a.Logger.Error("error querying api key", zap.Error(err))
+		return nil, err
+	}
+
+	return &Principal{ID: "key:" + key.KeyID, Name: key.Name, Scopes: key.Scopes}, nil
+}
+
+// HashKey returns the hex encoded SHA-256 of a key.
+// Keys are long random strings, so a fast hash is enough to make a leaked table useless.
+func HashKey(plainKey string) string {
+	sum := sha256.Sum256([]byte(plainKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateKey returns a new random key
+func generateKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPrincipal_HasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{name: "Test Case 1: Granted Scope", scopes: []string{ScopeScanRun}, scope: ScopeScanRun, want: true},
+		{name: "Test Case 2: Missing Scope", scopes: []string{ScopeHistoryRead}, scope: ScopeScanRun, want: false},
+		{name: "Test Case 3: Admin Has Every Scope", scopes: []string{ScopeAdmin}, scope: ScopeHistoryRead, want: true},
+		{name: "Test Case 4: No Scopes", scopes: nil, scope: ScopeScanRun, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := &Principal{Scopes: tt.scopes}
+			assert.Equal(t, tt.want, principal.HasScope(tt.scope))
+		})
+	}
+}
+
+func Test_generateKey(t *testing.T) {
+	first, err := generateKey()
+	require.NoError(t, err)
+	second, err := generateKey()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, keyPrefix))
+	assert.NotEqual(t, first, second)
+	assert.NotEqual(t, first, HashKey(first), "the stored hash must not be the key")
+	assert.Equal(t, HashKey(first), HashKey(first))
+}
+
+func TestAuthClient_AuthenticateKey(t *testing.T) {
+	client := NewAuthClient(zap.NewNop(), nil, "static-admin-key")
+
+	principal, err := client.AuthenticateKey(context.Background(), "static-admin-key")
+	require.NoError(t, err)
+	assert.True(t, principal.HasScope(ScopeAdmin))
+
+	_, err = client.AuthenticateKey(context.Background(), "")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
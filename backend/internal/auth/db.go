@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout MySQL uses for timestamp columns
+const timeLayout = "2006-01-02 15:04:05"
+
+// ErrKeyNotFound is returned when an API key does not exist or was revoked.
+var ErrKeyNotFound = errors.New("api key not found")
+
+// IDBClient is an interface that defines the methods for interacting with the APIKeys table.
+type IDBClient interface {
+	InsertKey(ctx context.Context, key *APIKey, keyHash string) error
+	QueryKeys(ctx context.Context) ([]*APIKey, error)
+	QueryKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	RevokeKey(ctx context.Context, keyID string) error
+}
+
+// DBClient is a struct that implements the IDBClient interface.
+type DBClient struct {
+	DB     *sql.DB
+	Logger *zap.Logger
+}
+
+// NewDBClient creates a new instance of DBClient sharing an existing database connection.
+func NewDBClient(conn *sql.DB, logger *zap.Logger) *DBClient {
+	return &DBClient{
+		DB:     conn,
+		Logger: logger,
+	}
+}
+
+const selectKeys = `SELECT key_id, name, prefix, scopes, created_at, revoked_at FROM APIKeys`
+
+// InsertKey inserts an API key and the hash of its secret in the database and sets its ID and creation time.
+func (db *DBClient) InsertKey(ctx context.Context, key *APIKey, keyHash string) error {
+	key.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	queryString := `INSERT INTO APIKeys (name, prefix, key_hash, scopes, created_at) VALUES (?, ?, ?, ?, ?)`
+	res, err := db.DB.ExecContext(ctx, queryString, key.Name, key.Prefix, keyHash, strings.Join(key.Scopes, ","), key.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	key.KeyID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// QueryKeys queries the database for all the API keys, including the revoked ones.
+func (db *DBClient) QueryKeys(ctx context.Context) ([]*APIKey, error) {
+	rows, err := db.DB.QueryContext(ctx, selectKeys+` ORDER BY key_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// QueryKeyByHash queries the database for the active API key with the given hash.
+func (db *DBClient) QueryKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	rows, err := db.DB.QueryContext(ctx, selectKeys+` WHERE key_hash = ? AND revoked_at IS NULL`, keyHash)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	return scanKey(rows)
+}
+
+// RevokeKey marks an API key as revoked, it can't be used anymore.
+func (db *DBClient) RevokeKey(ctx context.Context, keyID string) error {
+	res, err := db.DB.ExecContext(ctx, `UPDATE APIKeys SET revoked_at = ? WHERE key_id = ? AND revoked_at IS NULL`, time.Now().UTC().Truncate(time.Second), keyID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// scanKey scans the current row into an APIKey
+func scanKey(rows *sql.Rows) (*APIKey, error) {
+	var key APIKey
+	var scopes, createdAtStr string
+	var revokedAtStr sql.NullString
+	err := rows.Scan(&key.KeyID, &key.Name, &key.Prefix, &scopes, &createdAtStr, &revokedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Split(scopes, ",")
+
+	if key.CreatedAt, err = time.Parse(timeLayout, createdAtStr); err != nil {
+		return nil, err
+	}
+	if revokedAtStr.Valid {
+		revokedAt, err := time.Parse(timeLayout, revokedAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &revokedAt
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,320 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// jwksRefreshInterval is how long fetched signing keys are trusted before being fetched again
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefreshInterval limits how often the keys are fetched, whether the last attempt succeeded or not
+	jwksMinRefreshInterval = time.Minute
+)
+
+// JWTConfig represents the settings used to validate bearer tokens
+type JWTConfig struct {
+	Issuer     string              // Expected iss claim
+	Audience   string              // Expected aud claim, not checked when empty
+	JWKSURL    string              // URL of the issuer's JWKS
+	JWKSFile   string              // Local JWKS file, used instead of the URL for offline setups
+	RolesClaim string              // Claim holding the roles, nested claims are separated by dots (e.g. realm_access.roles)
+	RoleScopes map[string][]string // Scopes granted to each role
+}
+
+// jwk represents a single JSON Web Key
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWTVerifier represents a validator of bearer tokens signed by a configured issuer
+type JWTVerifier struct {
+	Logger     *zap.Logger  // Logger
+	Config     JWTConfig    // Token settings
+	HTTPClient *http.Client // HTTP client used to fetch the JWKS
+
+	mu          sync.Mutex                  // Guards keys and fetchedAt
+	keys        map[string]crypto.PublicKey // Signing keys by key ID
+	fetchedAt   time.Time                   // When keys were last loaded
+	refreshMu   sync.Mutex                  // Serializes refreshes and guards attemptedAt
+	attemptedAt time.Time                   // When keys were last refreshed, successfully or not
+	now         func() time.Time            // Clock, replaced in tests
+}
+
+// NewJWTVerifier creates a new JWTVerifier and loads the signing keys
+func NewJWTVerifier(logger *zap.Logger, config JWTConfig) (*JWTVerifier, error) {
+	if config.JWKSURL == "" && config.JWKSFile == "" {
+		return nil, fmt.Errorf("a JWKS URL or file is required")
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+
+	verifier := &JWTVerifier{
+		Logger:     logger,
+		Config:     config,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+
+	verifier.attemptedAt = verifier.now()
+	if err := verifier.loadKeys(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return verifier, nil
+}
+
+// Authenticate validates a bearer token and returns its principal, with the scopes mapped from its roles
+func (v *JWTVerifier) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithIssuer(v.Config.Issuer),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if v.Config.Audience != "" {
+		options = append(options, jwt.WithAudience(v.Config.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	}, options...)
+	if err != nil {
+		v.Logger.Debug("invalid bearer token", zap.Error(err))
+		return nil, ErrUnauthenticated
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	name := subject
+	for _, claim := range []string{"preferred_username", "email"} {
+		if value, ok := claims[claim].(string); ok && value != "" {
+			name = value
+			break
+		}
+	}
+
+	return &Principal{
+		ID:     "jwt:" + subject,
+		Name:   name,
+		Scopes: v.scopes(claims),
+	}, nil
+}
+
+// scopes maps the roles of a token to the scopes they grant
+func (v *JWTVerifier) scopes(claims jwt.MapClaims) []string {
+	var value any = map[string]any(claims)
+	for _, part := range strings.Split(v.Config.RolesClaim, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+
+	var roles []string
+	switch value := value.(type) {
+	case string:
+		roles = strings.Fields(value)
+	case []any:
+		for _, role := range value {
+			if role, ok := role.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, role := range roles {
+		for _, scope := range v.Config.RoleScopes[role] {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// key returns the signing key with the given ID, refreshing the keys when it is unknown or they are stale
+func (v *JWTVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	stale := v.now().Sub(v.fetchedAt) > jwksRefreshInterval
+	v.mu.Unlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	// Only a JWKS URL can have gained a key since the last load, a file is reloaded when stale
+	if stale || v.Config.JWKSURL != "" {
+		v.refresh(ctx)
+
+		v.mu.Lock()
+		key, ok = v.keys[kid]
+		v.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// refresh reloads the signing keys unless a refresh was attempted within jwksMinRefreshInterval, so neither unknown
+// key IDs nor an unreachable issuer cause a fetch per request. Concurrent callers wait for the same refresh.
+// Stale keys are kept when the refresh fails.
+func (v *JWTVerifier) refresh(ctx context.Context) {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	now := v.now()
+	if now.Sub(v.attemptedAt) < jwksMinRefreshInterval {
+		return
+	}
+	v.attemptedAt = now
+
+	if err := v.loadKeys(ctx); err != nil {
+		v.Logger.Error("error loading JWKS", zap.Error(err))
+	}
+}
+
+// loadKeys loads the signing keys from the JWKS file or URL
+func (v *JWTVerifier) loadKeys(ctx context.Context) error {
+	var data []byte
+	var err error
+	if v.Config.JWKSFile != "" {
+		data, err = os.ReadFile(v.Config.JWKSFile)
+	} else {
+		data, err = v.fetchKeys(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys = keys
+	v.fetchedAt = v.now()
+	return nil
+}
+
+// fetchKeys downloads the JWKS from the configured URL
+func (v *JWTVerifier) fetchKeys(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Config.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := v.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d fetching JWKS", res.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+}
+
+// parseJWKS parses the RSA and EC signing keys of a JWKS document, other keys are ignored
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, err := decodeBigInt(k.N)
+			if err != nil {
+				return nil, err
+			}
+			e, err := decodeBigInt(k.E)
+			if err != nil {
+				return nil, err
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, err := decodeBigInt(k.X)
+			if err != nil {
+				return nil, err
+			}
+			y, err := decodeBigInt(k.Y)
+			if err != nil {
+				return nil, err
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no usable signing keys")
+	}
+	return keys, nil
+}
+
+// decodeBigInt decodes a base64url encoded big endian integer
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWK value: %w", err)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
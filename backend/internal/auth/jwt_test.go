@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestJWTVerifier_Authenticate(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// Write the public key as a local JWKS file, the offline setup
+	jwks := map[string]any{
+		"keys": []map[string]string{
+			{
+				"kid": "test-key",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+			},
+		},
+	}
+	data, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksFile, data, 0o600))
+
+	verifier, err := NewJWTVerifier(zap.NewNop(), JWTConfig{
+		Issuer:     "https://idp.example.com",
+		Audience:   "nmap_project",
+		JWKSFile:   jwksFile,
+		RolesClaim: "realm_access.roles",
+		RoleScopes: map[string][]string{
+			"scanner": {ScopeScanRun, ScopeHistoryRead},
+			"auditor": {ScopeHistoryRead},
+		},
+	})
+	require.NoError(t, err)
+
+	sign := func(key *rsa.PrivateKey, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test-key"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":                "https://idp.example.com",
+			"aud":                "nmap_project",
+			"sub":                "user-1",
+			"preferred_username": "jdoe",
+			"exp":                time.Now().Add(time.Hour).Unix(),
+			"realm_access":       map[string]any{"roles": []string{"scanner", "auditor"}},
+		}
+	}
+
+	t.Run("Test Case 1: Valid Token", func(t *testing.T) {
+		principal, err := verifier.Authenticate(context.Background(), sign(privateKey, validClaims()))
+		require.NoError(t, err)
+		assert.Equal(t, "jwt:user-1", principal.ID)
+		assert.Equal(t, "jdoe", principal.Name)
+		assert.Equal(t, []string{ScopeScanRun, ScopeHistoryRead}, principal.Scopes)
+	})
+
+	t.Run("Test Case 2: Wrong Issuer", func(t *testing.T) {
+		claims := validClaims()
+		claims["iss"] = "https://evil.example.com"
+		_, err := verifier.Authenticate(context.Background(), sign(privateKey, claims))
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("Test Case 3: Wrong Audience", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = "another_service"
+		_, err := verifier.Authenticate(context.Background(), sign(privateKey, claims))
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("Test Case 4: Expired Token", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		_, err := verifier.Authenticate(context.Background(), sign(privateKey, claims))
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("Test Case 5: Signed With Another Key", func(t *testing.T) {
+		_, err := verifier.Authenticate(context.Background(), sign(otherKey, validClaims()))
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("Test Case 6: Unknown Roles Grant Nothing", func(t *testing.T) {
+		claims := validClaims()
+		claims["realm_access"] = map[string]any{"roles": []string{"guest"}}
+		principal, err := verifier.Authenticate(context.Background(), sign(privateKey, claims))
+		require.NoError(t, err)
+		assert.Empty(t, principal.Scopes)
+	})
+}
+
+func TestJWTVerifier_FailingJWKS(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]any{
+		"keys": []map[string]string{
+			{
+				"kid": "test-key",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// The issuer serves its keys once and is down from then on
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	verifier, err := NewJWTVerifier(zap.NewNop(), JWTConfig{Issuer: "https://idp.example.com", JWKSURL: server.URL})
+	require.NoError(t, err)
+	now := time.Now()
+	verifier.now = func() time.Time { return now }
+
+	lookup := func(kid string) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = verifier.key(context.Background(), kid)
+			}()
+		}
+		wg.Wait()
+	}
+
+	// Unknown key IDs right after the keys were loaded don't fetch them again
+	lookup("unknown")
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// Concurrent requests share a single fetch, which fails
+	now = now.Add(jwksMinRefreshInterval + time.Second)
+	lookup("unknown")
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// The failed attempt throttles the next ones as much as a successful one
+	now = now.Add(jwksMinRefreshInterval / 2)
+	lookup("unknown")
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// Stale keys are refreshed once per interval while the issuer is down, and kept meanwhile
+	now = now.Add(jwksRefreshInterval)
+	lookup("test-key")
+	assert.Equal(t, int32(3), fetches.Load())
+	key, err := verifier.key(context.Background(), "test-key")
+	require.NoError(t, err)
+	assert.Equal(t, &privateKey.PublicKey, key)
+	assert.Equal(t, int32(3), fetches.Load())
+}
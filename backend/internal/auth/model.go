@@ -0,0 +1,59 @@
+package auth
+
+import "time"
+
+const (
+	// ScopeScanRun allows launching scans
+	ScopeScanRun = "scan:run"
+	// ScopeHistoryRead allows reading scan history, tags and policy violations
+	ScopeHistoryRead = "history:read"
+	// ScopeAdmin grants every other scope and allows managing keys, workspaces and reading the audit log
+	ScopeAdmin = "admin"
+)
+
+// APIKey represents a stored API key, the key itself is only kept as a hash
+type APIKey struct {
+	KeyID     string     `db:"key_id" json:"key_id"`
+	Name      string     `db:"name" json:"name"`
+	Prefix    string     `db:"prefix" json:"prefix"` // First characters of the key, to tell keys apart
+	Scopes    []string   `db:"scopes" json:"scopes"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// CreateKeyRequest represents a request to create an API key
+type CreateKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=255"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=scan:run history:read admin"`
+}
+
+// CreateKeyResponse represents a newly created API key, the only time the plain key is returned
+type CreateKeyResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// Principal represents the authenticated caller of a request
+type Principal struct {
+	ID     string   // Identifier of the caller, e.g. the API key ID
+	Name   string   // Human readable name of the caller
+	Scopes []string // Scopes granted to the caller
+}
+
+// String returns the identity of the principal as recorded on the actions it takes
+func (p *Principal) String() string {
+	if p.Name == "" || p.Name == p.ID {
+		return p.ID
+	}
+	return p.ID + " (" + p.Name + ")"
+}
+
+// HasScope checks if the principal was granted a scope, admins are granted every scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
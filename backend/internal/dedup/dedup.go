@@ -0,0 +1,101 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrPanicked is returned to the callers sharing a call that panicked
+var ErrPanicked = errors.New("shared call panicked")
+
+// Group deduplicates calls by key.
+// A call made while another one with the same key is running waits for it and shares its result,
+// and a successful result keeps being returned for the Cooldown that follows.
+type Group[T any] struct {
+	Cooldown time.Duration // How long a successful result is reused
+
+	mu     sync.Mutex
+	calls  map[string]*call[T]
+	now    func() time.Time
+	onWait func() // Called when a call starts waiting on a running one, lets tests synchronise
+}
+
+// call is a running or recently finished call
+type call[T any] struct {
+	done     chan struct{}
+	value    T
+	err      error
+	finished time.Time // Zero while the call is running
+}
+
+// NewGroup creates a Group reusing successful results for the cooldown
+func NewGroup[T any](cooldown time.Duration) *Group[T] {
+	return &Group[T]{
+		Cooldown: cooldown,
+		calls:    make(map[string]*call[T]),
+		now:      time.Now,
+	}
+}
+
+// Do runs fn unless a call with the same key is running or finished successfully within the cooldown,
+// in which case it returns that call's result instead. The returned bool reports whether the result was shared.
+// Waiting for a running call stops when the context ends, the running call itself carries on.
+func (g *Group[T]) Do(ctx context.Context, key string, fn func() (T, error)) (T, bool, error) {
+	g.mu.Lock()
+	g.sweep(g.now())
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		if g.onWait != nil {
+			g.onWait()
+		}
+
+		select {
+		case <-c.done:
+			return c.value, true, c.err
+		case <-ctx.Done():
+			var zero T
+			return zero, false, ctx.Err()
+		}
+	}
+
+	c := &call[T]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// The call is released even if fn panics, otherwise every later call with the key would wait on it forever
+	defer func() {
+		r := recover()
+		if r != nil {
+			c.err = fmt.Errorf("%w: %v", ErrPanicked, r)
+		}
+
+		g.mu.Lock()
+		// Failures are never reused so the next call tries again
+		if c.err != nil || g.Cooldown <= 0 {
+			delete(g.calls, key)
+		} else {
+			c.finished = g.now()
+		}
+		g.mu.Unlock()
+		close(c.done)
+
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	c.value, c.err = fn()
+	return c.value, false, c.err
+}
+
+// sweep drops the finished calls whose cooldown is over
+func (g *Group[T]) sweep(now time.Time) {
+	for key, c := range g.calls {
+		if !c.finished.IsZero() && now.Sub(c.finished) >= g.Cooldown {
+			delete(g.calls, key)
+		}
+	}
+}
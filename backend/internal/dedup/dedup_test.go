@@ -0,0 +1,134 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupSharesRunningCall(t *testing.T) {
+	group := NewGroup[int](0)
+
+	// Let the running call finish once the second call is waiting on it
+	release := make(chan struct{})
+	group.onWait = func() { close(release) }
+
+	started := make(chan struct{})
+	go group.Do(context.Background(), "10.0.0.1", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+
+	value, shared, err := group.Do(context.Background(), "10.0.0.1", func() (int, error) {
+		return 2, nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, shared)
+	assert.Equal(t, 1, value)
+}
+
+func TestGroupCooldown(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	group := NewGroup[int](time.Minute)
+	group.now = func() time.Time { return now }
+
+	calls := 0
+	fn := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	value, shared, err := group.Do(context.Background(), "10.0.0.1", fn)
+	assert.NoError(t, err)
+	assert.False(t, shared)
+	assert.Equal(t, 1, value)
+
+	// Within the cooldown the previous result is reused
+	now = now.Add(30 * time.Second)
+	value, shared, _ = group.Do(context.Background(), "10.0.0.1", fn)
+	assert.True(t, shared)
+	assert.Equal(t, 1, value)
+
+	// Other keys are not affected
+	value, shared, _ = group.Do(context.Background(), "10.0.0.2", fn)
+	assert.False(t, shared)
+	assert.Equal(t, 2, value)
+
+	// Once the cooldown is over the call runs again
+	now = now.Add(time.Minute)
+	value, shared, _ = group.Do(context.Background(), "10.0.0.1", fn)
+	assert.False(t, shared)
+	assert.Equal(t, 3, value)
+}
+
+func TestGroupDoesNotReuseErrors(t *testing.T) {
+	group := NewGroup[int](time.Minute)
+
+	_, _, err := group.Do(context.Background(), "10.0.0.1", func() (int, error) {
+		return 0, errors.New("nmap failed")
+	})
+	assert.Error(t, err)
+
+	value, shared, err := group.Do(context.Background(), "10.0.0.1", func() (int, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, shared)
+	assert.Equal(t, 1, value)
+}
+
+func TestGroupReleasesPanickedCall(t *testing.T) {
+	group := NewGroup[int](time.Minute)
+
+	release := make(chan struct{})
+	group.onWait = func() { close(release) }
+
+	started := make(chan struct{})
+	panicked := make(chan any)
+	go func() {
+		defer func() { panicked <- recover() }()
+		group.Do(context.Background(), "10.0.0.1", func() (int, error) {
+			close(started)
+			<-release
+			panic("nmap output unreadable")
+		})
+	}()
+	<-started
+
+	// The waiting call gets an error and the panic still reaches the caller that ran it
+	_, shared, err := group.Do(context.Background(), "10.0.0.1", func() (int, error) { return 2, nil })
+	assert.ErrorIs(t, err, ErrPanicked)
+	assert.True(t, shared)
+	assert.Equal(t, "nmap output unreadable", <-panicked)
+
+	// The next call runs again instead of waiting on the panicked one
+	group.onWait = nil
+	value, shared, err := group.Do(context.Background(), "10.0.0.1", func() (int, error) { return 3, nil })
+	assert.NoError(t, err)
+	assert.False(t, shared)
+	assert.Equal(t, 3, value)
+}
+
+func TestGroupWaitCancelled(t *testing.T) {
+	group := NewGroup[int](0)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go group.Do(context.Background(), "10.0.0.1", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := group.Do(ctx, "10.0.0.1", func() (int, error) { return 2, nil })
+	assert.ErrorIs(t, err, context.Canceled)
+}
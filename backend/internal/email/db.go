@@ -0,0 +1,197 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout MySQL uses for timestamp columns
+const timeLayout = "2006-01-02 15:04:05"
+
+// ErrSubscriberNotFound is returned when a subscriber does not exist.
+var ErrSubscriberNotFound = errors.New("email subscriber not found")
+
+// IDBClient is an interface that defines the methods for interacting with the email tables.
+type IDBClient interface {
+	InsertSubscriber(ctx context.Context, subscriber *Subscriber) error
+	QuerySubscribers(ctx context.Context, workspaceID string) ([]*Subscriber, error)
+	QueryDigestSubscribers(ctx context.Context) ([]*Subscriber, error)
+	DeleteSubscriber(ctx context.Context, workspaceID string, subscriberID string) error
+	InsertDigestAlert(ctx context.Context, subscriberID string, alert Alert) error
+	QueryDigestAlerts(ctx context.Context, subscriberID string) ([]string, []Alert, error)
+	DeleteDigestAlerts(ctx context.Context, alertIDs []string) error
+}
+
+// DBClient is a struct that implements the IDBClient interface.
+type DBClient struct {
+	DB     *sql.DB
+	Logger *zap.Logger
+}
+
+// NewDBClient creates a new instance of DBClient sharing an existing database connection.
+func NewDBClient(conn *sql.DB, logger *zap.Logger) *DBClient {
+	return &DBClient{
+		DB:     conn,
+		Logger: logger,
+	}
+}
+
+// InsertSubscriber inserts a subscriber in the database and sets its ID and creation time.
+func (db *DBClient) InsertSubscriber(ctx context.Context, subscriber *Subscriber) error {
+	subscriber.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	queryString := `INSERT INTO EmailSubscribers (workspace_id, email, host, digest, created_at) VALUES (?, ?, ?, ?, ?)`
+	res, err := db.DB.ExecContext(ctx, queryString, subscriber.WorkspaceID, subscriber.Email, subscriber.Host, subscriber.Digest, subscriber.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	subscriber.SubscriberID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// QuerySubscribers queries the database for all the email subscribers of a workspace.
+func (db *DBClient) QuerySubscribers(ctx context.Context, workspaceID string) ([]*Subscriber, error) {
+	return db.querySubscribers(ctx, selectSubscribers+` WHERE workspace_id = ? ORDER BY subscriber_id`, workspaceID)
+}
+
+// QueryDigestSubscribers queries the database for the daily digest subscribers of every workspace.
+func (db *DBClient) QueryDigestSubscribers(ctx context.Context) ([]*Subscriber, error) {
+	return db.querySubscribers(ctx, selectSubscribers+` WHERE digest = TRUE ORDER BY subscriber_id`)
+}
+
+const selectSubscribers = `SELECT subscriber_id, workspace_id, email, host, digest, created_at FROM EmailSubscribers`
+
+// querySubscribers runs a query returning subscribers
+func (db *DBClient) querySubscribers(ctx context.Context, queryString string, args ...any) ([]*Subscriber, error) {
+	rows, err := db.DB.QueryContext(ctx, queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	subscribers := []*Subscriber{}
+	for rows.Next() {
+		var subscriber Subscriber
+		var createdAtStr string
+		err := rows.Scan(&subscriber.SubscriberID, &subscriber.WorkspaceID, &subscriber.Email, &subscriber.Host, &subscriber.Digest, &createdAtStr)
+		if err != nil {
+			return nil, err
+		}
+
+		if subscriber.CreatedAt, err = time.Parse(timeLayout, createdAtStr); err != nil {
+			return nil, err
+		}
+
+		subscribers = append(subscribers, &subscriber)
+	}
+
+	return subscribers, rows.Err()
+}
+
+// DeleteSubscriber deletes a subscriber of a workspace and its pending digest alerts from the database.
+func (db *DBClient) DeleteSubscriber(ctx context.Context, workspaceID string, subscriberID string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE a FROM EmailDigestAlerts a JOIN EmailSubscribers s ON s.subscriber_id = a.subscriber_id
+		WHERE s.workspace_id = ? AND a.subscriber_id = ?`, workspaceID, subscriberID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM EmailSubscribers WHERE workspace_id = ? AND subscriber_id = ?`, workspaceID, subscriberID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return ErrSubscriberNotFound
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
+
+// InsertDigestAlert queues an alert for the next daily digest of a subscriber.
+func (db *DBClient) InsertDigestAlert(ctx context.Context, subscriberID string, alert Alert) error {
+	alertJSON, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	queryString := `INSERT INTO EmailDigestAlerts (subscriber_id, alert, created_at) VALUES (?, ?, ?)`
+	_, err = db.DB.ExecContext(ctx, queryString, subscriberID, string(alertJSON), alert.Timestamp)
+	return err
+}
+
+// QueryDigestAlerts queries the alerts queued for a subscriber, oldest first, along with their IDs.
+func (db *DBClient) QueryDigestAlerts(ctx context.Context, subscriberID string) ([]string, []Alert, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT alert_id, alert FROM EmailDigestAlerts WHERE subscriber_id = ? ORDER BY alert_id`, subscriberID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer rows.Close()
+
+	var alertIDs []string
+	var alerts []Alert
+	for rows.Next() {
+		var alertID, alertJSON string
+		if err := rows.Scan(&alertID, &alertJSON); err != nil {
+			return nil, nil, err
+		}
+
+		var alert Alert
+		if err := json.Unmarshal([]byte(alertJSON), &alert); err != nil {
+			return nil, nil, err
+		}
+
+		alertIDs = append(alertIDs, alertID)
+		alerts = append(alerts, alert)
+	}
+
+	return alertIDs, alerts, rows.Err()
+}
+
+// DeleteDigestAlerts deletes alerts once they have been sent.
+func (db *DBClient) DeleteDigestAlerts(ctx context.Context, alertIDs []string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, alertID := range alertIDs {
+		_, err = tx.ExecContext(ctx, `DELETE FROM EmailDigestAlerts WHERE alert_id = ?`, alertID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
@@ -0,0 +1,262 @@
+package email
+
+import (
+	"backend/internal/policy"
+	"backend/internal/scan"
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Parse(`{{range .}}Host: {{.Host.IPAddress}}{{if .Host.Hostname}} ({{.Host.Hostname}}){{end}}
+Scanned: {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+{{if .HostChange}}Host status change: {{.HostChange}}
+{{end}}{{if .NameChanges}}Reverse DNS changes:
+{{range .NameChanges}}  - {{.Name}}: {{.Change}}
+{{end}}{{end}}{{if .Changes}}Changed ports:
+{{range .Changes}}  - {{.Port}}: {{.Change}}
+{{end}}{{end}}{{if .Violations}}Policy violations:
+{{range .Violations}}  - {{.RuleName}}: {{.Message}}
+{{end}}{{end}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<html><body>
+{{range .}}<h3>{{.Host.IPAddress}}{{if .Host.Hostname}} ({{.Host.Hostname}}){{end}}</h3>
+<p>Scanned: {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}</p>
+{{if .HostChange}}<p>Host status change: {{.HostChange}}</p>{{end}}
+{{if .NameChanges}}<table border="1" cellpadding="4"><tr><th>Reverse DNS name</th><th>Change</th></tr>
+{{range .NameChanges}}<tr><td>{{.Name}}</td><td>{{.Change}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Changes}}<table border="1" cellpadding="4"><tr><th>Port</th><th>Change</th></tr>
+{{range .Changes}}<tr><td>{{.Port}}</td><td>{{.Change}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Violations}}<table border="1" cellpadding="4"><tr><th>Rule</th><th>Violation</th></tr>
+{{range .Violations}}<tr><td>{{.RuleName}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>{{end}}
+{{end}}</body></html>`))
+
+// EmailClient represents a client for emailing scan alerts to subscribers
+type EmailClient struct {
+	Logger   *zap.Logger // Logger
+	DBClient *DBClient   // Database client
+	Config   Config      // SMTP settings
+}
+
+// NewEmailClient creates a new EmailClient
+func NewEmailClient(logger *zap.Logger, DBClient *DBClient, config Config) *EmailClient {
+	return &EmailClient{
+		Logger:   logger,
+		DBClient: DBClient,
+		Config:   config,
+	}
+}
+
+// NotifyScan emails the changes and policy violations of a scan to the subscribers of its host.
+// Digest subscribers get the alert queued for their next daily digest instead.
+func (e *EmailClient) NotifyScan(ctx context.Context, response *scan.ScanResponse, violations []*policy.Violation) {
+	if len(response.Changes) == 0 && response.HostChange == "" && len(response.NameChanges) == 0 && len(violations) == 0 {
+		return
+	}
+
+	subscribers, err := e.DBClient.QuerySubscribers(ctx, response.WorkspaceID)
+	if err != nil {
+		e.Logger.Error("error querying email subscribers", zap.Error(err))
+		return
+	}
+
+	alert := Alert{
+		Host:        response.Host,
+		Timestamp:   time.Now().UTC().Truncate(time.Second),
+		Changes:     response.PortChanges(),
+		HostChange:  response.HostChange,
+		NameChanges: response.NameChanges,
+		Violations:  violations,
+	}
+
+	var recipients []string
+	for _, subscriber := range subscribers {
+		if subscriber.Host != "" && subscriber.Host != response.Host.IPAddress && subscriber.Host != response.Host.Hostname {
+			continue
+		}
+
+		if subscriber.Digest {
+			if err := e.DBClient.InsertDigestAlert(ctx, subscriber.SubscriberID, alert); err != nil {
+				e.Logger.Error("error queueing digest alert", zap.String("email", subscriber.Email), zap.Error(err))
+			}
+			continue
+		}
+
+		recipients = append(recipients, subscriber.Email)
+	}
+
+	if len(recipients) == 0 {
+		return
+	}
+
+	// Send in the background so the scan request isn't held up by the SMTP server
+	go func() {
+		if err := e.send(recipients, alertSubject(alert), []Alert{alert}); err != nil {
+			e.Logger.Error("error sending alert email", zap.Strings("recipients", recipients), zap.Error(err))
+		}
+	}()
+}
+
+// alertSubject describes what an alert contains, e.g. "Port changes and policy violations detected on 1.2.3.4"
+func alertSubject(alert Alert) string {
+	var found []string
+	if len(alert.Changes) > 0 {
+		found = append(found, "port changes")
+	}
+	if len(alert.Violations) > 0 {
+		found = append(found, "policy violations")
+	}
+	if len(alert.NameChanges) > 0 {
+		found = append(found, "reverse DNS changes")
+	}
+
+	list := ""
+	for i, item := range found {
+		switch {
+		case i == 0:
+			list = item
+		case i == len(found)-1:
+			list += " and " + item
+		default:
+			list += ", " + item
+		}
+	}
+
+	var subject string
+	switch alert.HostChange {
+	case scan.HostChangeDown:
+		subject = fmt.Sprintf("Host %s went down", alert.Host.IPAddress)
+	case scan.HostChangeUp:
+		subject = fmt.Sprintf("Host %s came back up", alert.Host.IPAddress)
+	default:
+		if list == "" {
+			return fmt.Sprintf("Changes detected on %s", alert.Host.IPAddress)
+		}
+		return fmt.Sprintf("%s%s detected on %s", strings.ToUpper(list[:1]), list[1:], alert.Host.IPAddress)
+	}
+
+	if list != "" {
+		subject += " with " + list
+	}
+	return subject
+}
+
+// RunDigest sends the daily digests at the configured hour until the context is cancelled
+func (e *EmailClient) RunDigest(ctx context.Context) {
+	for {
+		timer := time.NewTimer(time.Until(nextDigestTime(time.Now().UTC(), e.Config.DigestHour)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			e.sendDigests(ctx)
+		}
+	}
+}
+
+// sendDigests sends every digest subscriber the alerts queued since their last digest
+func (e *EmailClient) sendDigests(ctx context.Context) {
+	subscribers, err := e.DBClient.QueryDigestSubscribers(ctx)
+	if err != nil {
+		e.Logger.Error("error querying email subscribers", zap.Error(err))
+		return
+	}
+
+	for _, subscriber := range subscribers {
+		alertIDs, alerts, err := e.DBClient.QueryDigestAlerts(ctx, subscriber.SubscriberID)
+		if err != nil {
+			e.Logger.Error("error querying digest alerts", zap.String("email", subscriber.Email), zap.Error(err))
+			continue
+		}
+
+		if len(alerts) == 0 {
+			continue
+		}
+
+		subject := fmt.Sprintf("Daily port change digest: %d scans with changes", len(alerts))
+		if err := e.send([]string{subscriber.Email}, subject, alerts); err != nil {
+			e.Logger.Error("error sending digest email", zap.String("email", subscriber.Email), zap.Error(err))
+			continue
+		}
+
+		if err := e.DBClient.DeleteDigestAlerts(ctx, alertIDs); err != nil {
+			e.Logger.Error("error deleting digest alerts", zap.String("email", subscriber.Email), zap.Error(err))
+		}
+	}
+}
+
+// send emails a summary of the alerts to the recipients through the configured SMTP server
+func (e *EmailClient) send(recipients []string, subject string, alerts []Alert) error {
+	message, err := buildMessage(e.Config.From, recipients, subject, alerts)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if e.Config.Username != "" {
+		auth = smtp.PlainAuth("", e.Config.Username, e.Config.Password, e.Config.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(e.Config.Host, e.Config.Port), auth, e.Config.From, recipients, message)
+}
+
+// buildMessage builds a multipart email with a plain text and an HTML summary of the alerts
+func buildMessage(from string, recipients []string, subject string, alerts []Alert) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	textPart, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return nil, err
+	}
+	if err := textTemplate.Execute(textPart, alerts); err != nil {
+		return nil, err
+	}
+
+	htmlPart, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	if err != nil {
+		return nil, err
+	}
+	if err := htmlTemplate.Execute(htmlPart, alerts); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// nextDigestTime returns the next time at the given hour of the day after now
+func nextDigestTime(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,168 @@
+package email
+
+import (
+	"backend/internal/policy"
+	"backend/internal/scan"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// smtpSink is a minimal SMTP server that accepts a single message and hands it back on a channel
+func smtpSink(t *testing.T) (string, string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP sink")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+
+			switch command := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); command {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "DATA":
+				tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+				data, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				messages <- strings.Join(data, "\n")
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 OK")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	return host, port, messages
+}
+
+func TestEmailClient_send(t *testing.T) {
+	host, port, messages := smtpSink(t)
+
+	client := NewEmailClient(zap.NewNop(), nil, Config{Host: host, Port: port, From: "scanner@example.com"})
+
+	alerts := []Alert{
+		{
+			Host:        scan.Host{IPAddress: "1.2.3.4", Hostname: "www.example.com"},
+			Timestamp:   time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			Changes:     []scan.PortChange{{Port: 3389, Change: "added"}},
+			NameChanges: []scan.NameChange{{Name: "rdp.example.net", Change: scan.NameChangePTRAdded}},
+			Violations: []*policy.Violation{
+				{RuleName: "no rdp", Message: "port 3389 must never be open"},
+			},
+		},
+	}
+
+	err := client.send([]string{"oncall@example.com"}, "Port changes detected on 1.2.3.4", alerts)
+	require.NoError(t, err)
+
+	select {
+	case message := <-messages:
+		assert.Contains(t, message, "To: oncall@example.com")
+		assert.Contains(t, message, "Subject: Port changes detected on 1.2.3.4")
+		assert.Contains(t, message, "Content-Type: text/plain; charset=UTF-8")
+		assert.Contains(t, message, "Content-Type: text/html; charset=UTF-8")
+		assert.Contains(t, message, "  - 3389: added")
+		assert.Contains(t, message, "<tr><td>3389</td><td>added</td></tr>")
+		assert.Contains(t, message, "  - rdp.example.net: ptr_added")
+		assert.Contains(t, message, "no rdp: port 3389 must never be open")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received by the SMTP sink")
+	}
+}
+
+func Test_nextDigestTime(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		hour int
+		want time.Time
+	}{
+		{
+			name: "Test Case 1: Later Today",
+			now:  time.Date(2021, 1, 1, 6, 30, 0, 0, time.UTC),
+			hour: 8,
+			want: time.Date(2021, 1, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Test Case 2: Tomorrow",
+			now:  time.Date(2021, 1, 1, 8, 0, 0, 0, time.UTC),
+			hour: 8,
+			want: time.Date(2021, 1, 2, 8, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextDigestTime(tt.now, tt.hour))
+		})
+	}
+}
+
+func Test_alertSubject(t *testing.T) {
+	host := scan.Host{IPAddress: "1.2.3.4"}
+	changes := []scan.PortChange{{Port: 22, Change: "added"}}
+	violations := []*policy.Violation{{IPAddress: "1.2.3.4", Port: 22}}
+	nameChanges := []scan.NameChange{{Name: "host.example.com", Change: "ptr_added"}}
+
+	tests := []struct {
+		name  string
+		alert Alert
+		want  string
+	}{
+		{
+			name:  "Test Case 1: Port Changes",
+			alert: Alert{Host: host, Changes: changes},
+			want:  "Port changes detected on 1.2.3.4",
+		},
+		{
+			name:  "Test Case 2: Policy Violations Only",
+			alert: Alert{Host: host, Violations: violations},
+			want:  "Policy violations detected on 1.2.3.4",
+		},
+		{
+			name:  "Test Case 3: Everything",
+			alert: Alert{Host: host, Changes: changes, Violations: violations, NameChanges: nameChanges},
+			want:  "Port changes, policy violations and reverse DNS changes detected on 1.2.3.4",
+		},
+		{
+			name:  "Test Case 4: Host Down",
+			alert: Alert{Host: host, HostChange: scan.HostChangeDown},
+			want:  "Host 1.2.3.4 went down",
+		},
+		{
+			name:  "Test Case 5: Host Up With Port Changes",
+			alert: Alert{Host: host, HostChange: scan.HostChangeUp, Changes: changes},
+			want:  "Host 1.2.3.4 came back up with port changes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, alertSubject(tt.alert))
+		})
+	}
+}
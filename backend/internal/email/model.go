@@ -0,0 +1,38 @@
+package email
+
+import (
+	"backend/internal/policy"
+	"backend/internal/scan"
+	"time"
+)
+
+// Subscriber represents an email address subscribed to scan alerts.
+// A subscriber without a host receives the alerts of every host.
+type Subscriber struct {
+	SubscriberID string    `db:"subscriber_id" json:"subscriber_id,omitempty"`
+	WorkspaceID  string    `db:"workspace_id" json:"workspace_id"`
+	Email        string    `db:"email" json:"email" validate:"required,email,max=255"`
+	Host         string    `db:"host" json:"host,omitempty" validate:"omitempty,ip|fqdn"` // Only alert for this IP address or hostname
+	Digest       bool      `db:"digest" json:"digest"`                                    // Receive a daily digest instead of one email per scan
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// Alert represents the changes and policy violations found by a single scan
+type Alert struct {
+	Host        scan.Host           `json:"host"`
+	Timestamp   time.Time           `json:"timestamp"`
+	Changes     []scan.PortChange   `json:"changes"`
+	HostChange  string              `json:"host_change,omitempty"`  // Set when the host went down or came back
+	NameChanges []scan.NameChange   `json:"name_changes,omitempty"` // Reverse DNS changes of the host
+	Violations  []*policy.Violation `json:"violations"`
+}
+
+// Config represents the SMTP server settings used to send alerts
+type Config struct {
+	Host       string // SMTP server host
+	Port       string // SMTP server port
+	Username   string // SMTP username, authentication is skipped when empty
+	Password   string // SMTP password
+	From       string // Sender address
+	DigestHour int    // Hour of the day (UTC) the daily digest is sent
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/email"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) postEmailSubscriberHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var subscriber email.Subscriber
+	if err := c.ShouldBindJSON(&subscriber); err != nil {
+		s.Logger.Error("unable to bind json", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+
+	if err := validate.Struct(subscriber); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	subscriber.WorkspaceID = getWorkspaceID(c)
+	if err := s.EmailClient.DBClient.InsertSubscriber(ctx, &subscriber); err != nil {
+		s.Logger.Error("unable to insert email subscriber", zap.Error(err))
+		abortWithError(c, storeError("Unable to create email subscriber", err))
+		return
+	}
+
+	setAuditTarget(c, subscriber.SubscriberID)
+	c.JSON(http.StatusCreated, subscriber)
+}
+
+func (s *Server) getEmailSubscribersHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	subscribers, err := s.EmailClient.DBClient.QuerySubscribers(ctx, getWorkspaceID(c))
+	if err != nil {
+		s.Logger.Error("unable to query email subscribers", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query email subscribers", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, subscribers)
+}
+
+func (s *Server) deleteEmailSubscriberHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.EmailClient.DBClient.DeleteSubscriber(ctx, getWorkspaceID(c), c.Param("id"))
+	if errors.Is(err, email.ErrSubscriberNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to delete email subscriber", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to delete email subscriber", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
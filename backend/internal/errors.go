@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDuplicateEntry is the MySQL error number of a unique key violation
+const mysqlDuplicateEntry = 1062
+
+// ErrorResponse is the body of every error answered by the API
+type ErrorResponse struct {
+	Error *apierror.Error `json:"error"`
+}
+
+// validate validates request bodies, reporting invalid fields by their JSON name
+var validate = newValidator()
+
+// newValidator creates a validator naming fields after their JSON name, or query parameter name for query filters
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+				return name
+			}
+		}
+		return ""
+	})
+	return v
+}
+
+// abortWithError answers a request with the API error wrapped in err, or an internal error
+func abortWithError(c *gin.Context, err error) {
+	apiErr := apierror.From(err)
+	c.AbortWithStatusJSON(apiErr.Status(), ErrorResponse{Error: apiErr})
+}
+
+// storeError maps an error of a database write to an API error.
+// Unique key violations are conflicts, anything else is an internal error with the given message.
+func storeError(message string, err error) *apierror.Error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return &apierror.Error{Code: apierror.CodeConflict, Message: "Resource already exists", Err: err}
+	}
+	return apierror.Internal(message, err)
+}
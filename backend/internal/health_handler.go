@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"backend/internal/scan"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds the time spent on each readiness check
+const readinessTimeout = 2 * time.Second
+
+// HealthCheck is the result of a single dependency check
+type HealthCheck struct {
+	Status  string `json:"status"`            // ok or unavailable
+	Version string `json:"version,omitempty"` // Version of the dependency, when known
+	Error   string `json:"error,omitempty"`
+}
+
+// HealthResponse is the result of a health or readiness probe
+type HealthResponse struct {
+	Status string                 `json:"status"` // ok or unavailable
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// getHealthzHandler reports the process is alive, it doesn't check any dependency so a failing database doesn't get the pod restarted
+func (s *Server) getHealthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// getReadyzHandler reports whether the service can take scan requests
+func (s *Server) getReadyzHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]HealthCheck{
+		"database":  newHealthCheck("", s.DBClient.DB.PingContext(ctx)),
+		"scheduler": newHealthCheck("", s.Scheduler.Check(time.Now())),
+	}
+
+	version, err := scan.NmapVersion(ctx)
+	checks["nmap"] = newHealthCheck(version, err)
+
+	if queue := s.ScanClient.Queue; queue != nil {
+		var err error
+		if queue.Waiting() >= queue.MaxWaiting {
+			err = errors.New("nmap queue is full")
+		}
+		checks["queue"] = newHealthCheck("", err)
+	}
+
+	response := HealthResponse{Status: "ok", Checks: checks}
+	for _, check := range checks {
+		if check.Status != "ok" {
+			response.Status = "unavailable"
+		}
+	}
+
+	if response.Status != "ok" {
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// newHealthCheck builds the result of a check from its error
+func newHealthCheck(version string, err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{Status: "unavailable", Error: err.Error()}
+	}
+	return HealthCheck{Status: "ok", Version: version}
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/scan"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) getHostTagsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ipAddress := c.Param("ip")
+	if net.ParseIP(ipAddress) == nil {
+		abortWithError(c, apierror.Invalid("ip", "ip", "A valid IP address is required"))
+		return
+	}
+
+	tags, err := s.DBClient.QueryHostTags(ctx, getWorkspaceID(c), ipAddress)
+	if err != nil {
+		s.Logger.Error("unable to query host tags", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query host tags", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, scan.HostTagsRequest{Tags: tags})
+}
+
+func (s *Server) putHostTagsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ipAddress := c.Param("ip")
+	if net.ParseIP(ipAddress) == nil {
+		abortWithError(c, apierror.Invalid("ip", "ip", "A valid IP address is required"))
+		return
+	}
+
+	var tagsRequest scan.HostTagsRequest
+	if err := c.ShouldBindJSON(&tagsRequest); err != nil {
+		s.Logger.Error("unable to bind json", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+
+	if err := validate.Struct(tagsRequest); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	err := s.DBClient.UpdateHostTags(ctx, getWorkspaceID(c), ipAddress, tagsRequest.Tags)
+	if errors.Is(err, scan.ErrHostNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to update host tags", zap.Error(err))
+		abortWithError(c, storeError("Unable to update host tags", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, tagsRequest)
+}
+
+// getHostAvailabilityHandler returns the periods during which a host was up or down, newest first
+func (s *Server) getHostAvailabilityHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ipAddress := c.Param("ip")
+	if net.ParseIP(ipAddress) == nil {
+		abortWithError(c, apierror.Invalid("ip", "ip", "A valid IP address is required"))
+		return
+	}
+
+	runs, err := s.DBClient.QueryScanRuns(ctx, getWorkspaceID(c), ipAddress)
+	if err != nil {
+		s.Logger.Error("unable to query scan runs", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query host availability", err))
+		return
+	}
+	if len(runs) == 0 {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, scan.ErrHostNotFound))
+		return
+	}
+
+	c.JSON(http.StatusOK, scan.AvailabilityHistory(runs))
+}
+
+// getHostNamesHandler returns every name reported for a host, most recently seen first
+func (s *Server) getHostNamesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ipAddress := c.Param("ip")
+	if net.ParseIP(ipAddress) == nil {
+		abortWithError(c, apierror.Invalid("ip", "ip", "A valid IP address is required"))
+		return
+	}
+
+	names, err := s.DBClient.QueryHostNames(ctx, getWorkspaceID(c), ipAddress)
+	if err != nil {
+		s.Logger.Error("unable to query host names", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query host names", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, names)
+}
@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/importer"
+	"backend/internal/scan"
+	"backend/internal/scope"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxImportSize is the maximum size of an uploaded file
+const maxImportSize = 10 << 20
+
+// ScanImportResponse represents the scans recorded from an uploaded report, one per host
+type ScanImportResponse struct {
+	Scans []*ScanResponse `json:"scans"`
+}
+
+// postTargetImportHandler imports an uploaded target list into the host inventory and answers with a report of every target.
+// Scans of the imported targets are queued in the background when requested.
+func (s *Server) postTargetImportHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	workspaceID := getWorkspaceID(c)
+
+	var importRequest importer.TargetImportRequest
+	file, filename, ok := bindUpload(c, &importRequest)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	if err := validate.Struct(importRequest); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	setAuditTarget(c, filename)
+
+	format := importRequest.Format
+	if format == "" {
+		format = importer.FormatFromFilename(filename)
+	}
+
+	targets, invalidLines, err := importer.ParseTargets(file, format)
+	if err != nil {
+		abortWithError(c, apierror.Invalid("file", "format", err.Error()))
+		return
+	}
+
+	report := &importer.TargetImportReport{Lines: []*importer.LineReport{}}
+	for _, line := range invalidLines {
+		report.Add(line)
+	}
+
+	seen := make(map[string]bool)
+	var imported []string
+	for _, target := range targets {
+		line, err := s.importTarget(ctx, workspaceID, target, seen)
+		if err != nil {
+			s.Logger.Error("unable to import host", zap.String("target", target.Target), zap.Error(err))
+			abortWithError(c, apierror.Internal(fmt.Sprintf("Unable to import target %s on line %d", target.Target, target.Line), err))
+			return
+		}
+
+		report.Add(line)
+		if line.Status == importer.LineImported {
+			imported = append(imported, target.Target)
+		}
+	}
+
+	sort.SliceStable(report.Lines, func(i, j int) bool {
+		return report.Lines[i].Line < report.Lines[j].Line
+	})
+
+	if importRequest.Scan && len(imported) > 0 {
+		initiatedBy := "import"
+		if principal := getPrincipal(c); principal != nil {
+			initiatedBy = "import:" + principal.String()
+		}
+
+		// The scans outlive the request, a 500 host list takes a while
+		go func() {
+			if err := s.scanTargets(context.WithoutCancel(ctx), workspaceID, imported, importRequest.Profile, initiatedBy); err != nil {
+				s.Logger.Error("error scanning imported targets", zap.String("file", filename), zap.Error(err))
+			}
+		}()
+		report.ScansQueued = len(imported)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// postReportImportHandler records every host of an uploaded scan report in a format as a scan run
+// and answers with the changes found for each of them
+func (s *Server) postReportImportHandler(format string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var importRequest importer.ReportImportRequest
+		file, filename, ok := bindUpload(c, &importRequest)
+		if !ok {
+			return
+		}
+		defer file.Close()
+
+		if err := validate.Struct(importRequest); err != nil {
+			s.Logger.Error("validation error", zap.Error(err))
+			abortWithError(c, apierror.Validation(err))
+			return
+		}
+
+		setAuditTarget(c, filename)
+
+		report, err := io.ReadAll(file)
+		if err != nil {
+			s.Logger.Error("unable to read uploaded file", zap.Error(err))
+			abortWithError(c, apierror.Internal("Unable to read the uploaded file", err))
+			return
+		}
+
+		req := scan.ScanRequestMapped{Profile: importRequest.Profile, WorkspaceID: getWorkspaceID(c)}
+		if principal := getPrincipal(c); principal != nil {
+			req.InitiatedBy = principal.String()
+		}
+
+		scanResponses, err := s.ScanClient.ImportReport(ctx, req, format, report)
+		if err != nil {
+			s.Logger.Error("unable to import scan report", zap.String("format", format), zap.String("file", filename), zap.Error(err))
+			abortWithError(c, err)
+			return
+		}
+
+		response := ScanImportResponse{Scans: make([]*ScanResponse, 0, len(scanResponses))}
+		for _, scanResponse := range scanResponses {
+			response.Scans = append(response.Scans, s.processScan(ctx, scanResponse))
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// importTarget validates a target of an imported list, checks it is in scope and adds every address it resolves to to the inventory.
+// Only database failures are returned as errors, invalid and out of scope targets are reported in the line report.
+func (s *Server) importTarget(ctx context.Context, workspaceID string, target *importer.Target, seen map[string]bool) (*importer.LineReport, error) {
+	line := &importer.LineReport{Line: target.Line, Target: target.Target, Status: importer.LineImported}
+
+	if err := validate.Struct(target); err != nil {
+		line.Status = importer.LineInvalid
+		line.Errors = apierror.Validation(err).Fields
+		return line, nil
+	}
+
+	key := strings.ToLower(target.Target)
+	if seen[key] {
+		line.Status = importer.LineDuplicate
+		return line, nil
+	}
+	seen[key] = true
+
+	// Targets that could never be scanned don't belong in the inventory, hostnames are added with the addresses checked
+	addresses, err := s.Scope.Check(ctx, target.Target)
+	var scopeErr *scope.Error
+	if errors.As(err, &scopeErr) {
+		line.Status = importer.LineInvalid
+		line.Errors = []apierror.FieldError{{Field: "target", Rule: string(apierror.CodeOutOfScope), Message: "Out of scope: " + scopeErr.Reason}}
+		return line, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	host := scan.Host{Owner: target.Owner}
+	if net.ParseIP(target.Target) == nil {
+		host.Hostname = target.Target
+	}
+	line.Addresses = addresses
+
+	for _, address := range line.Addresses {
+		host.IPAddress = address
+		if err := s.DBClient.ImportHost(ctx, workspaceID, host, target.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	return line, nil
+}
+
+// bindUpload binds the form fields of a file upload and opens the uploaded file, answering the request when it is invalid
+func bindUpload(c *gin.Context, form any) (multipart.File, string, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var maxBytesErr *http.MaxBytesError
+	err := c.ShouldBind(form)
+	if errors.As(err, &maxBytesErr) {
+		abortWithError(c, apierror.Invalid("file", "max", fmt.Sprintf("The file must be at most %d MiB", maxImportSize>>20)))
+		return nil, "", false
+	}
+	if err != nil {
+		abortWithError(c, apierror.Binding(err))
+		return nil, "", false
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		abortWithError(c, apierror.Invalid("file", "required", "A file is required"))
+		return nil, "", false
+	}
+
+	return file, header.Filename, true
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/importer"
+	"backend/internal/scope"
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hostsResolver resolves hostnames from a static map, like a hosts file
+type hostsResolver map[string]string
+
+func (r hostsResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ip, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+}
+
+func TestServer_importTargetOutOfScope(t *testing.T) {
+	targetScope, err := scope.NewScope([]string{"10.0.0.0/8"}, []string{"example.com"}, nil)
+	require.NoError(t, err)
+	targetScope.Resolver = hostsResolver{"www.other.com": "203.0.113.10"}
+	s := &Server{Scope: targetScope}
+
+	tests := []struct {
+		name   string
+		target *importer.Target
+		rule   string
+	}{
+		{name: "Test Case 1: Denied Address", target: &importer.Target{Line: 1, Target: "127.0.0.1"}, rule: string(apierror.CodeOutOfScope)},
+		{name: "Test Case 2: Address Outside Allowed Ranges", target: &importer.Target{Line: 2, Target: "93.184.216.34"}, rule: string(apierror.CodeOutOfScope)},
+		{name: "Test Case 3: Hostname Outside Allowed Domains", target: &importer.Target{Line: 3, Target: "www.other.com"}, rule: string(apierror.CodeOutOfScope)},
+		{name: "Test Case 4: Invalid Target", target: &importer.Target{Line: 4, Target: "not a host"}, rule: "ip|fqdn"},
+		{name: "Test Case 5: Unresolvable Hostname", target: &importer.Target{Line: 5, Target: "missing.other.com"}, rule: string(apierror.CodeOutOfScope)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := s.importTarget(context.Background(), "1", tt.target, map[string]bool{})
+			require.NoError(t, err)
+			assert.Equal(t, importer.LineInvalid, line.Status)
+			assert.Equal(t, tt.target.Line, line.Line)
+			require.Len(t, line.Errors, 1)
+			assert.Equal(t, "target", line.Errors[0].Field)
+			assert.Equal(t, tt.rule, line.Errors[0].Rule)
+			assert.Empty(t, line.Addresses)
+		})
+	}
+}
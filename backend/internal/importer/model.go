@@ -0,0 +1,68 @@
+package importer
+
+import "backend/internal/apierror"
+
+// Formats of target lists
+const (
+	FormatText = "text" // One target per line
+	FormatCSV  = "csv"  // Columns target, tags and owner, named by a header row
+	FormatNmap = "nmap" // nmap -iL input, targets separated by spaces, tabs or newlines
+)
+
+// Outcomes of a target of an imported list
+const (
+	LineImported  = "imported"  // The target was added to the inventory or updated
+	LineInvalid   = "invalid"   // The target was rejected, see its errors
+	LineDuplicate = "duplicate" // The target already appeared earlier in the list
+)
+
+// Target represents a host listed in an imported target list
+type Target struct {
+	Line   int      `json:"-"`                                                      // Line of the list the target was read from
+	Target string   `json:"target" validate:"required,ip|fqdn"`                     // IP address or hostname
+	Tags   []string `json:"tags,omitempty" validate:"unique,dive,required,max=255"` // Tags to add to the host
+	Owner  string   `json:"owner,omitempty" validate:"max=255"`                     // Owner of the host
+}
+
+// TargetImportRequest represents the form fields sent along with an uploaded target list
+type TargetImportRequest struct {
+	Format  string `form:"format" validate:"omitempty,oneof=text csv nmap"`       // Format of the list, guessed from the file name when empty
+	Scan    bool   `form:"scan"`                                                  // Queue a scan of every imported target
+	Profile string `form:"profile" validate:"omitempty,oneof=default quick full"` // Profile of the queued scans
+}
+
+// ReportImportRequest represents the form fields sent along with an uploaded scan report
+type ReportImportRequest struct {
+	Profile string `form:"profile" validate:"omitempty,oneof=default quick full"` // Profile whose ports the report covers, changes are computed against scans with the same profile
+}
+
+// LineReport represents the outcome of a single target of an imported list
+type LineReport struct {
+	Line      int                   `json:"line"`                // Line of the list the target was read from
+	Target    string                `json:"target,omitempty"`    // Target as written in the list
+	Status    string                `json:"status"`              // Outcome (imported, invalid, duplicate)
+	Addresses []string              `json:"addresses,omitempty"` // Addresses added to the inventory, several when a hostname resolves to more than one
+	Errors    []apierror.FieldError `json:"errors,omitempty"`    // Why the target was rejected
+}
+
+// TargetImportReport represents the outcome of a target list import
+type TargetImportReport struct {
+	Imported    int           `json:"imported"`     // Number of targets added to the inventory or updated
+	Invalid     int           `json:"invalid"`      // Number of targets rejected
+	Duplicates  int           `json:"duplicates"`   // Number of targets listed more than once
+	ScansQueued int           `json:"scans_queued"` // Number of scans queued, only when requested
+	Lines       []*LineReport `json:"lines"`        // Outcome of every target, in the order of the list
+}
+
+// Add records the outcome of a target
+func (r *TargetImportReport) Add(line *LineReport) {
+	switch line.Status {
+	case LineImported:
+		r.Imported++
+	case LineInvalid:
+		r.Invalid++
+	case LineDuplicate:
+		r.Duplicates++
+	}
+	r.Lines = append(r.Lines, line)
+}
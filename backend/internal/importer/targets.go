@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"backend/internal/apierror"
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// FormatFromFilename guesses the format of a target list from its file name, lists that aren't CSV files are read as text
+func FormatFromFilename(filename string) string {
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		return FormatCSV
+	}
+	return FormatText
+}
+
+// ParseTargets reads the targets of a list in a format. Comments start with '#' and blank lines are skipped.
+// Lines that can't be read are returned as invalid line reports, an error is only returned when the list can't be read at all.
+func ParseTargets(r io.Reader, format string) ([]*Target, []*LineReport, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSVTargets(r)
+	case FormatNmap:
+		return parseLineTargets(r, strings.Fields)
+	case FormatText, "":
+		return parseLineTargets(r, func(line string) []string { return []string{line} })
+	default:
+		return nil, nil, fmt.Errorf("unknown target list format %q", format)
+	}
+}
+
+// parseLineTargets reads the targets of a plain text list, split splits a line without its comment into targets
+func parseLineTargets(r io.Reader, split func(line string) []string) ([]*Target, []*LineReport, error) {
+	var targets []*Target
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		for _, target := range split(line) {
+			targets = append(targets, &Target{Line: lineNumber, Target: target})
+		}
+	}
+
+	return targets, nil, scanner.Err()
+}
+
+// parseCSVTargets reads the targets of a CSV list. The header row names the columns, target is required while tags,
+// separated by semicolons, and owner are optional. Other columns are ignored.
+func parseCSVTargets(r io.Reader) ([]*Target, []*LineReport, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["target"]; !ok {
+		return nil, nil, errors.New("the CSV header must have a target column")
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var targets []*Target
+	var invalid []*LineReport
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			invalid = append(invalid, &LineReport{
+				Line:   parseErr.Line,
+				Status: LineInvalid,
+				Errors: []apierror.FieldError{{Field: "line", Rule: "csv", Message: parseErr.Err.Error()}},
+			})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		record = stripComment(record)
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		target := &Target{Line: line, Target: column(record, "target"), Owner: column(record, "owner")}
+		for _, tag := range strings.Split(column(record, "tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				target.Tags = append(target.Tags, tag)
+			}
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, invalid, nil
+}
+
+// stripComment drops the comment of a CSV record, from the first '#' to the end of the row, as for plain text lists
+func stripComment(record []string) []string {
+	for i, field := range record {
+		if before, _, found := strings.Cut(field, "#"); found {
+			return append(record[:i:i], before)
+		}
+	}
+	return record
+}
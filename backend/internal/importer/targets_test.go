@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTargets_Text(t *testing.T) {
+	const list = `# web servers
+10.0.0.1
+www.example.com   # public site
+
+  2001:db8::1
+`
+
+	targets, invalid, err := ParseTargets(strings.NewReader(list), FormatText)
+	require.NoError(t, err)
+	assert.Empty(t, invalid)
+	assert.Equal(t, []*Target{
+		{Line: 2, Target: "10.0.0.1"},
+		{Line: 3, Target: "www.example.com"},
+		{Line: 5, Target: "2001:db8::1"},
+	}, targets)
+}
+
+func TestParseTargets_Nmap(t *testing.T) {
+	const list = "10.0.0.1 10.0.0.2\twww.example.com\n# db servers\n10.0.1.1 # primary\n"
+
+	targets, _, err := ParseTargets(strings.NewReader(list), FormatNmap)
+	require.NoError(t, err)
+	assert.Equal(t, []*Target{
+		{Line: 1, Target: "10.0.0.1"},
+		{Line: 1, Target: "10.0.0.2"},
+		{Line: 1, Target: "www.example.com"},
+		{Line: 3, Target: "10.0.1.1"},
+	}, targets)
+}
+
+func TestParseTargets_CSV(t *testing.T) {
+	const list = `Owner,Target,Tags
+# decommissioned in Q3
+ops@example.com,10.0.0.1,prod; web
+,www.example.com,
+dba@example.com,"10.0.1.1,extra"x,db
+,10.0.0.2,# no tags yet, owner,tbd
+,10.0.0.3,staging # moving to prod
+  # spare
+`
+
+	targets, invalid, err := ParseTargets(strings.NewReader(list), FormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, []*Target{
+		{Line: 3, Target: "10.0.0.1", Tags: []string{"prod", "web"}, Owner: "ops@example.com"},
+		{Line: 4, Target: "www.example.com"},
+		{Line: 6, Target: "10.0.0.2"},
+		{Line: 7, Target: "10.0.0.3", Tags: []string{"staging"}},
+	}, targets)
+	require.Len(t, invalid, 1)
+	assert.Equal(t, 5, invalid[0].Line)
+	assert.Equal(t, LineInvalid, invalid[0].Status)
+
+	_, _, err = ParseTargets(strings.NewReader("host,tags\n10.0.0.1,prod\n"), FormatCSV)
+	assert.Error(t, err, "a CSV list without a target column should be rejected")
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	assert.Equal(t, FormatCSV, FormatFromFilename("hosts.CSV"))
+	assert.Equal(t, FormatText, FormatFromFilename("hosts.txt"))
+	assert.Equal(t, FormatText, FormatFromFilename("targets"))
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) postKeyHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var keyRequest auth.CreateKeyRequest
+	if err := c.ShouldBindJSON(&keyRequest); err != nil {
+		s.Logger.Error("unable to bind json", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+
+	if err := validate.Struct(keyRequest); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	key, err := s.AuthClient.CreateKey(ctx, keyRequest)
+	if err != nil {
+		abortWithError(c, storeError("Unable to create api key", err))
+		return
+	}
+
+	setAuditTarget(c, key.KeyID)
+	c.JSON(http.StatusCreated, key)
+}
+
+func (s *Server) getKeysHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	keys, err := s.AuthClient.DBClient.QueryKeys(ctx)
+	if err != nil {
+		s.Logger.Error("unable to query api keys", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query api keys", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func (s *Server) deleteKeyHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.AuthClient.DBClient.RevokeKey(ctx, c.Param("id"))
+	if errors.Is(err, auth.ErrKeyNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to revoke api key", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to revoke api key", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "nmap_project"
+
+// Scan outcomes
+const (
+	OutcomeSuccess      = "success"
+	OutcomeDeduplicated = "deduplicated"
+	OutcomeOutOfScope   = "out_of_scope"
+	OutcomeRejected     = "rejected" // The nmap queue was full
+	OutcomeFailed       = "failed"
+)
+
+var (
+	// ScansTotal counts the scan requests by outcome
+	ScansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scans_total",
+		Help:      "Scan requests by outcome.",
+	}, []string{"outcome"})
+
+	// NmapDuration observes how long nmap runs take by profile
+	NmapDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "nmap_duration_seconds",
+		Help:      "Duration of nmap runs.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800},
+	}, []string{"profile"})
+
+	// PortsDiscovered counts the open ports reported by scans
+	PortsDiscovered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ports_discovered_total",
+		Help:      "Open ports reported by scans.",
+	})
+
+	// ChangesTotal counts the port changes detected by type
+	ChangesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "changes_total",
+		Help:      "Port changes detected by type.",
+	}, []string{"change"})
+
+	// DBQueryDuration observes the latency of database queries by query
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of database queries.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"query"})
+
+	// HTTPRequestsTotal counts the HTTP requests by route, method and status code
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPRequestDuration observes the latency of HTTP requests by route and method
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+	}, []string{"route", "method"})
+)
+
+// ObserveDBQuery records the latency of a database query started at start, meant to be deferred
+func ObserveDBQuery(query string, start time.Time) {
+	DBQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+}
+
+// RegisterQueue exposes the number of running and waiting scans of the nmap queue
+func RegisterQueue(running func() int, waiting func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_scans",
+		Help:      "nmap processes currently running.",
+	}, func() float64 { return float64(running()) })
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Scans waiting for a free nmap slot.",
+	}, func() float64 { return float64(waiting()) })
+}
@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/workspace"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// APIKeyHeader is the header carrying the API key of a request
+	APIKeyHeader = "X-API-Key"
+	// RequestIDHeader is the header carrying the ID of a request
+	RequestIDHeader = "X-Request-ID"
+	// WorkspaceHeader is the header selecting the workspace of a request
+	WorkspaceHeader = "X-Workspace-ID"
+	// principalKey is the gin context key the authenticated caller is stored under
+	principalKey = "principal"
+	// workspaceKey is the gin context key the workspace of a request is stored under
+	workspaceKey = "workspace"
+	// roleKey is the gin context key the role of the caller in the workspace of a request is stored under
+	roleKey = "workspace_role"
+	// auditTargetKey is the gin context key handlers store the audited target under
+	auditTargetKey = "audit_target"
+	// requestIDKey is the gin context key the request ID is stored under
+	requestIDKey = "request_id"
+)
+
+// requestIDPattern matches the request IDs accepted from callers
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// authenticate rejects requests without valid credentials and stores the caller in the context.
+// Requests authenticate with either a bearer token, when an issuer is configured, or an API key.
+func (s *Server) authenticate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var principal *auth.Principal
+	var err error
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && s.JWTVerifier != nil {
+		principal, err = s.JWTVerifier.Authenticate(ctx, strings.TrimSpace(token))
+	} else {
+		principal, err = s.AuthClient.AuthenticateKey(ctx, c.GetHeader(APIKeyHeader))
+	}
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		s.deny(c, apierror.Wrap(apierror.CodeUnauthenticated, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to authenticate request", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to authenticate request", err))
+		return
+	}
+
+	c.Set(principalKey, principal)
+	c.Next()
+}
+
+// requireScope rejects requests whose caller wasn't granted the scope
+func (s *Server) requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := getPrincipal(c)
+		if principal == nil || !principal.HasScope(scope) {
+			s.deny(c, apierror.New(apierror.CodeForbidden, "Missing scope "+scope))
+			return
+		}
+		c.Next()
+	}
+}
+
+// getPrincipal returns the authenticated caller of a request, or nil
+func getPrincipal(c *gin.Context) *auth.Principal {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil
+	}
+	principal, _ := value.(*auth.Principal)
+	return principal
+}
+
+// resolveWorkspace stores the workspace of a request, and the role of the caller in it, in the context.
+// Callers pick a workspace with the X-Workspace-ID header, which can be left out when they belong to a single one.
+// Every caller, admins included, only acts on the workspaces it is a member of.
+func (s *Server) resolveWorkspace(c *gin.Context) {
+	ctx := c.Request.Context()
+	principal := getPrincipal(c)
+	workspaceID := c.GetHeader(WorkspaceHeader)
+
+	if workspaceID == "" {
+		workspaces, err := s.WorkspaceDBClient.QueryWorkspacesForPrincipal(ctx, principal.ID)
+		if err != nil {
+			s.Logger.Error("unable to query workspaces", zap.Error(err))
+			abortWithError(c, apierror.Internal("Unable to resolve workspace", err))
+			return
+		}
+
+		if len(workspaces) != 1 {
+			abortWithError(c, apierror.New(apierror.CodeInvalidRequest, "Missing "+WorkspaceHeader+" header"))
+			return
+		}
+		workspaceID = workspaces[0].WorkspaceID
+	}
+
+	member, err := s.WorkspaceDBClient.QueryMember(ctx, workspaceID, principal.ID)
+	if errors.Is(err, workspace.ErrMemberNotFound) {
+		s.deny(c, apierror.New(apierror.CodeForbidden, "Not a member of workspace "+workspaceID))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to query workspace member", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to resolve workspace", err))
+		return
+	}
+
+	c.Set(workspaceKey, workspaceID)
+	c.Set(roleKey, member.Role)
+	c.Next()
+}
+
+// requireWorkspaceAdmin rejects requests whose caller isn't an admin of the workspace of the request
+func (s *Server) requireWorkspaceAdmin(c *gin.Context) {
+	if c.GetString(roleKey) != workspace.RoleAdmin {
+		s.deny(c, apierror.New(apierror.CodeForbidden, "Not an admin of workspace "+getWorkspaceID(c)))
+		return
+	}
+	c.Next()
+}
+
+// getWorkspaceID returns the workspace of a request
+func getWorkspaceID(c *gin.Context) string {
+	return c.GetString(workspaceKey)
+}
+
+// audit records the action of a request in the audit log once it has been handled.
+// The target defaults to the resource in the path, handlers can override it with setAuditTarget.
+// The source IP is the peer address, forwarded headers only count when the peer is a trusted proxy.
+func (s *Server) audit(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		// The entry is written even if the client went away, the action may already have happened
+		s.recordAudit(context.WithoutCancel(c.Request.Context()), auditEntry(c, action))
+	}
+}
+
+// deny rejects a request its caller isn't allowed to make and records the attempt in the audit log
+func (s *Server) deny(c *gin.Context, err *apierror.Error) {
+	abortWithError(c, err)
+	s.recordAudit(context.WithoutCancel(c.Request.Context()), auditEntry(c, audit.ActionAccessDenied))
+}
+
+// auditEntry describes a handled request as an audit log entry
+func auditEntry(c *gin.Context, action string) *audit.Entry {
+	target := c.GetString(auditTargetKey)
+	if target == "" {
+		target = c.Param("id") + c.Param("ip")
+	}
+
+	entry := &audit.Entry{
+		WorkspaceID: getWorkspaceID(c),
+		Action:      action,
+		Target:      target,
+		Method:      c.Request.Method,
+		Path:        c.Request.URL.Path,
+		StatusCode:  c.Writer.Status(),
+		IPAddress:   c.ClientIP(),
+	}
+	if principal := getPrincipal(c); principal != nil {
+		entry.Actor = principal.String()
+	}
+	return entry
+}
+
+// setAuditTarget sets the target recorded in the audit log for a request
+func setAuditTarget(c *gin.Context, target string) {
+	c.Set(auditTargetKey, target)
+}
+
+// recordAudit appends an entry to the audit log, failures are logged as the action itself already happened
+func (s *Server) recordAudit(ctx context.Context, entry *audit.Entry) {
+	entry.Timestamp = time.Now().UTC().Truncate(time.Second)
+	if err := s.AuditDBClient.InsertEntry(ctx, entry); err != nil {
+		s.Logger.Error("unable to record audit entry", zap.String("action", entry.Action), zap.String("actor", entry.Actor), zap.Error(err))
+	}
+}
+
+// rateLimit rejects requests once the caller or its source IP used up its request budget
+func (s *Server) rateLimit(c *gin.Context) {
+	if s.IPLimiter != nil {
+		if allowed, retryAfter := s.IPLimiter.Allow(c.ClientIP()); !allowed {
+			abortTooManyRequests(c, retryAfter, apierror.New(apierror.CodeRateLimited, "Too many requests from this IP address"))
+			return
+		}
+	}
+
+	if principal := getPrincipal(c); s.KeyLimiter != nil && principal != nil {
+		if allowed, retryAfter := s.KeyLimiter.Allow(principal.ID); !allowed {
+			abortTooManyRequests(c, retryAfter, apierror.New(apierror.CodeRateLimited, "Too many requests for this API key"))
+			return
+		}
+	}
+
+	c.Next()
+}
+
+// abortTooManyRequests rejects a request with a 429 telling the client how many seconds to wait
+func abortTooManyRequests(c *gin.Context, retryAfter time.Duration, err error) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	abortWithError(c, err)
+}
+
+// observeRequest records the count and latency of requests by route
+func (s *Server) observeRequest(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	metrics.HTTPRequestsTotal.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(route, c.Request.Method).Observe(time.Since(start).Seconds())
+}
+
+// requestID tags every request with an ID, taken from the X-Request-ID header when the caller sent a valid one.
+// The ID is echoed in the response and carried by the request context so every log line of the request includes it.
+func (s *Server) requestID(c *gin.Context) {
+	requestID := c.GetHeader(RequestIDHeader)
+	if !requestIDPattern.MatchString(requestID) {
+		requestID = newRequestID()
+	}
+
+	c.Set(requestIDKey, requestID)
+	c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+	c.Header(RequestIDHeader, requestID)
+	c.Next()
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// accessLog logs every request once it has been served
+func (s *Server) accessLog(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	status := c.Writer.Status()
+	fields := []zap.Field{
+		zap.String("request_id", c.GetString(requestIDKey)),
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+		zap.String("route", c.FullPath()),
+		zap.Int("status", status),
+		zap.Duration("latency", time.Since(start)),
+		zap.String("client_ip", c.ClientIP()),
+		zap.Int("bytes", c.Writer.Size()),
+	}
+	if principal := getPrincipal(c); principal != nil {
+		fields = append(fields, zap.String("principal", principal.String()))
+	}
+
+	switch {
+	case status >= http.StatusInternalServerError:
+		s.Logger.Error("request served", fields...)
+	case status >= http.StatusBadRequest:
+		s.Logger.Warn("request served", fields...)
+	default:
+		s.Logger.Info("request served", fields...)
+	}
+}
+
+// recoverPanic turns a panicking handler into a 500 instead of dropping the connection
+func (s *Server) recoverPanic(c *gin.Context) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.FromContext(c.Request.Context(), s.Logger).Error("panic while handling request",
+				zap.Any("panic", recovered), zap.String("path", c.Request.URL.Path), zap.Stack("stack"))
+			abortWithError(c, apierror.New(apierror.CodeInternal, "Internal server error"))
+		}
+	}()
+	c.Next()
+}
@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/ratelimit"
+	"backend/internal/workspace"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRateLimit_IgnoresForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewServer(gin.New())
+	s.IPLimiter = ratelimit.NewLimiter(1, 1)
+	s.Router.POST("/scan", s.rateLimit, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/scan", nil)
+		req.RemoteAddr = "203.0.113.10:40000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A new X-Forwarded-For on every request must not buy a new bucket
+	assert.Equal(t, http.StatusOK, send("198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, send("198.51.100.2"))
+}
+
+func TestRateLimit_TrustedProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewServer(gin.New())
+	assert.NoError(t, s.Router.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	s.IPLimiter = ratelimit.NewLimiter(1, 1)
+	s.Router.POST("/scan", s.rateLimit, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/scan", nil)
+		req.RemoteAddr = "10.0.0.5:40000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Behind a trusted proxy every client has its own bucket
+	assert.Equal(t, http.StatusOK, send("198.51.100.1"))
+	assert.Equal(t, http.StatusOK, send("198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, send("198.51.100.2"))
+}
+
+// auditRecorder keeps the audit entries in memory
+type auditRecorder struct {
+	entries []*audit.Entry
+}
+
+func (r *auditRecorder) InsertEntry(_ context.Context, entry *audit.Entry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *auditRecorder) QueryEntries(context.Context, audit.Filter) ([]*audit.Entry, error) {
+	return r.entries, nil
+}
+
+func TestAudit_IgnoresForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewServer(gin.New())
+	s.Logger = zap.NewNop()
+	recorder := &auditRecorder{}
+	s.AuditDBClient = recorder
+	s.Router.DELETE("/keys/:id", s.audit(audit.ActionKeyRevoke), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodDelete, "/keys/1", nil)
+	req.RemoteAddr = "203.0.113.10:40000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Set("X-Real-IP", "198.51.100.1")
+	s.Router.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, recorder.entries, 1)
+	assert.Equal(t, "203.0.113.10", recorder.entries[0].IPAddress, "the audit log should record the peer, not a forged header")
+	assert.Equal(t, "1", recorder.entries[0].Target)
+}
+
+func TestAudit_DeniedScans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewServer(gin.New())
+	s.Logger = zap.NewNop()
+	recorder := &auditRecorder{}
+	s.AuditDBClient = recorder
+	s.IPLimiter = ratelimit.NewLimiter(1, 1)
+	var principal *auth.Principal
+	authenticate := func(c *gin.Context) { c.Set(principalKey, principal) }
+	s.Router.POST("/scan", authenticate, s.requireScope(auth.ScopeScanRun), s.audit(audit.ActionScanStart), s.rateLimit, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func() {
+		req := httptest.NewRequest(http.MethodPost, "/scan", nil)
+		req.RemoteAddr = "203.0.113.10:40000"
+		s.Router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// A caller without the scan scope is recorded as denied
+	principal = &auth.Principal{ID: "key:1", Scopes: []string{auth.ScopeHistoryRead}}
+	send()
+	require.Len(t, recorder.entries, 1)
+	assert.Equal(t, audit.ActionAccessDenied, recorder.entries[0].Action)
+	assert.Equal(t, "key:1", recorder.entries[0].Actor)
+	assert.Equal(t, http.StatusForbidden, recorder.entries[0].StatusCode)
+	assert.Equal(t, "/scan", recorder.entries[0].Path)
+	assert.Equal(t, "203.0.113.10", recorder.entries[0].IPAddress)
+
+	// Rate limited scans are recorded too
+	principal = &auth.Principal{ID: "key:2", Scopes: []string{auth.ScopeScanRun}}
+	send()
+	send()
+	require.Len(t, recorder.entries, 3)
+	assert.Equal(t, audit.ActionScanStart, recorder.entries[2].Action)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.entries[2].StatusCode)
+}
+
+// workspaceStore serves workspace memberships from memory, the other methods aren't used by the middleware
+type workspaceStore struct {
+	workspace.IDBClient
+	members []*workspace.Member
+}
+
+func (w *workspaceStore) QueryWorkspacesForPrincipal(_ context.Context, principalID string) ([]*workspace.Workspace, error) {
+	var workspaces []*workspace.Workspace
+	for _, member := range w.members {
+		if member.PrincipalID == principalID {
+			workspaces = append(workspaces, &workspace.Workspace{WorkspaceID: member.WorkspaceID})
+		}
+	}
+	return workspaces, nil
+}
+
+func (w *workspaceStore) QueryMember(_ context.Context, workspaceID, principalID string) (*workspace.Member, error) {
+	for _, member := range w.members {
+		if member.WorkspaceID == workspaceID && member.PrincipalID == principalID {
+			return member, nil
+		}
+	}
+	return nil, workspace.ErrMemberNotFound
+}
+
+func TestResolveWorkspace_Roles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewServer(gin.New())
+	s.Logger = zap.NewNop()
+	s.AuditDBClient = &auditRecorder{}
+	s.WorkspaceDBClient = &workspaceStore{members: []*workspace.Member{
+		{WorkspaceID: "2", PrincipalID: "key:1", Role: workspace.RoleAdmin},
+		{WorkspaceID: "3", PrincipalID: "key:2", Role: workspace.RoleMember},
+	}}
+	var principal *auth.Principal
+	authenticate := func(c *gin.Context) { c.Set(principalKey, principal) }
+	s.Router.GET("/policies", authenticate, s.resolveWorkspace, func(c *gin.Context) { c.String(http.StatusOK, getWorkspaceID(c)) })
+	s.Router.POST("/policies", authenticate, s.resolveWorkspace, s.requireWorkspaceAdmin, func(c *gin.Context) { c.String(http.StatusCreated, getWorkspaceID(c)) })
+
+	send := func(method, workspaceID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/policies", nil)
+		if workspaceID != "" {
+			req.Header.Set(WorkspaceHeader, workspaceID)
+		}
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		principal   *auth.Principal
+		method      string
+		workspaceID string
+		wantStatus  int
+		wantBody    string
+	}{
+		{name: "Test Case 1: Workspace Admin Defaults To Its Workspace", principal: &auth.Principal{ID: "key:1"}, method: http.MethodGet, wantStatus: http.StatusOK, wantBody: "2"},
+		{name: "Test Case 2: Workspace Admin Manages Its Workspace", principal: &auth.Principal{ID: "key:1"}, method: http.MethodPost, workspaceID: "2", wantStatus: http.StatusCreated, wantBody: "2"},
+		{name: "Test Case 3: Workspace Admin Reads Another Workspace", principal: &auth.Principal{ID: "key:1"}, method: http.MethodGet, workspaceID: "3", wantStatus: http.StatusForbidden, wantBody: string(apierror.CodeForbidden)},
+		{name: "Test Case 4: Workspace Admin Manages Another Workspace", principal: &auth.Principal{ID: "key:1"}, method: http.MethodPost, workspaceID: "3", wantStatus: http.StatusForbidden, wantBody: string(apierror.CodeForbidden)},
+		{name: "Test Case 5: Member Manages Its Workspace", principal: &auth.Principal{ID: "key:2"}, method: http.MethodPost, workspaceID: "3", wantStatus: http.StatusForbidden, wantBody: string(apierror.CodeForbidden)},
+		{name: "Test Case 6: Global Admin Outside Of Its Workspaces", principal: &auth.Principal{ID: "key:3", Scopes: []string{auth.ScopeAdmin}}, method: http.MethodGet, workspaceID: workspace.DefaultWorkspaceID, wantStatus: http.StatusForbidden, wantBody: string(apierror.CodeForbidden)},
+		{name: "Test Case 7: Unknown Workspace", principal: &auth.Principal{ID: "key:1"}, method: http.MethodGet, workspaceID: "42", wantStatus: http.StatusForbidden, wantBody: string(apierror.CodeForbidden)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal = tt.principal
+			w := send(tt.method, tt.workspaceID)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout MySQL uses for timestamp columns
+const timeLayout = "2006-01-02 15:04:05"
+
+// ErrRuleNotFound is returned when a rule does not exist.
+var ErrRuleNotFound = errors.New("policy rule not found")
+
+// IDBClient is an interface that defines the methods for interacting with the policy tables.
+type IDBClient interface {
+	InsertRule(ctx context.Context, rule *Rule) error
+	QueryRules(ctx context.Context, workspaceID string) ([]*Rule, error)
+	DeleteRule(ctx context.Context, workspaceID string, ruleID string) error
+	RecordViolations(ctx context.Context, workspaceID string, ipAddress string, found []*Violation, resolvedAt time.Time) ([]*Violation, error)
+	QueryViolations(ctx context.Context, filter ViolationFilter) ([]*Violation, error)
+}
+
+// DBClient is a struct that implements the IDBClient interface.
+type DBClient struct {
+	DB     *sql.DB
+	Logger *zap.Logger
+}
+
+// NewDBClient creates a new instance of DBClient sharing an existing database connection.
+func NewDBClient(conn *sql.DB, logger *zap.Logger) *DBClient {
+	return &DBClient{
+		DB:     conn,
+		Logger: logger,
+	}
+}
+
+// InsertRule inserts a rule in the database and sets its ID and creation time.
+func (db *DBClient) InsertRule(ctx context.Context, rule *Rule) error {
+	rule.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	queryString := `INSERT INTO PolicyRules (workspace_id, name, tag, allowed_ports, denied_ports, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := db.DB.ExecContext(ctx, queryString, rule.WorkspaceID, rule.Name, rule.Tag, joinPorts(rule.AllowedPorts), joinPorts(rule.DeniedPorts), rule.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	rule.RuleID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// QueryRules queries the database for all the policy rules of a workspace.
+func (db *DBClient) QueryRules(ctx context.Context, workspaceID string) ([]*Rule, error) {
+	queryString := `SELECT rule_id, workspace_id, name, tag, allowed_ports, denied_ports, created_at FROM PolicyRules WHERE workspace_id = ? ORDER BY rule_id`
+	rows, err := db.DB.QueryContext(ctx, queryString, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	rules := []*Rule{}
+	for rows.Next() {
+		var rule Rule
+		var allowedPorts, deniedPorts, createdAtStr string
+		err := rows.Scan(&rule.RuleID, &rule.WorkspaceID, &rule.Name, &rule.Tag, &allowedPorts, &deniedPorts, &createdAtStr)
+		if err != nil {
+			return nil, err
+		}
+
+		if rule.AllowedPorts, err = splitPorts(allowedPorts); err != nil {
+			return nil, err
+		}
+		if rule.DeniedPorts, err = splitPorts(deniedPorts); err != nil {
+			return nil, err
+		}
+		if rule.CreatedAt, err = time.Parse(timeLayout, createdAtStr); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, &rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// DeleteRule deletes a rule of a workspace and its violations from the database.
+func (db *DBClient) DeleteRule(ctx context.Context, workspaceID string, ruleID string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM PolicyViolations WHERE workspace_id = ? AND rule_id = ?`, workspaceID, ruleID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM PolicyRules WHERE workspace_id = ? AND rule_id = ?`, workspaceID, ruleID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return ErrRuleNotFound
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
+
+// RecordViolations records the violations found by a scan of a host against its open violations. Violations that are
+// already open are left as they are, new ones are inserted and open ones that weren't found again are resolved.
+// The new violations are returned with their IDs set.
+func (db *DBClient) RecordViolations(ctx context.Context, workspaceID string, ipAddress string, found []*Violation, resolvedAt time.Time) ([]*Violation, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the open violations of the host so concurrent scans of it don't record the same violation twice
+	queryString := `SELECT violation_id, rule_id, port FROM PolicyViolations WHERE workspace_id = ? AND ip_address = ? AND resolved_at IS NULL FOR UPDATE`
+	rows, err := tx.QueryContext(ctx, queryString, workspaceID, ipAddress)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var open []*Violation
+	for rows.Next() {
+		var violation Violation
+		if err := rows.Scan(&violation.ViolationID, &violation.RuleID, &violation.Port); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		open = append(open, &violation)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	added, resolved := diffViolations(open, found)
+
+	for _, violation := range added {
+		queryString := `INSERT INTO PolicyViolations (workspace_id, rule_id, ip_address, port, message, timestamp) VALUES (?, ?, ?, ?, ?, ?)`
+		res, err := tx.ExecContext(ctx, queryString, violation.WorkspaceID, violation.RuleID, violation.IPAddress, violation.Port, violation.Message, violation.Timestamp)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		violation.ViolationID = strconv.FormatInt(id, 10)
+	}
+
+	for _, violation := range resolved {
+		_, err := tx.ExecContext(ctx, `UPDATE PolicyViolations SET resolved_at = ? WHERE violation_id = ?`, resolvedAt, violation.ViolationID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Commit the transaction
+	return added, tx.Commit()
+}
+
+// QueryViolations queries the database for violations matching the filter, newest first.
+func (db *DBClient) QueryViolations(ctx context.Context, filter ViolationFilter) ([]*Violation, error) {
+	queryString := `SELECT v.violation_id, v.workspace_id, v.rule_id, r.name, v.ip_address, v.port, v.message, v.timestamp, v.resolved_at
+		FROM PolicyViolations v JOIN PolicyRules r ON r.rule_id = v.rule_id WHERE v.workspace_id = ?`
+	args := []any{filter.WorkspaceID}
+	if filter.IPAddress != "" {
+		queryString += ` AND v.ip_address = ?`
+		args = append(args, filter.IPAddress)
+	}
+	if filter.RuleID != "" {
+		queryString += ` AND v.rule_id = ?`
+		args = append(args, filter.RuleID)
+	}
+	queryString += ` ORDER BY v.timestamp DESC, v.violation_id DESC`
+
+	rows, err := db.DB.QueryContext(ctx, queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	violations := []*Violation{}
+	for rows.Next() {
+		var violation Violation
+		var timestampStr string
+		var resolvedAtStr sql.NullString
+		err := rows.Scan(&violation.ViolationID, &violation.WorkspaceID, &violation.RuleID, &violation.RuleName, &violation.IPAddress, &violation.Port, &violation.Message, &timestampStr, &resolvedAtStr)
+		if err != nil {
+			return nil, err
+		}
+
+		if violation.Timestamp, err = time.Parse(timeLayout, timestampStr); err != nil {
+			return nil, err
+		}
+		if resolvedAtStr.Valid {
+			resolvedAt, err := time.Parse(timeLayout, resolvedAtStr.String)
+			if err != nil {
+				return nil, err
+			}
+			violation.ResolvedAt = &resolvedAt
+		}
+
+		violations = append(violations, &violation)
+	}
+
+	return violations, rows.Err()
+}
+
+// joinPorts formats a list of ports as a comma separated string for storage
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitPorts parses a comma separated list of ports
+func splitPorts(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var ports []int
+	for _, part := range strings.Split(value, ",") {
+		port, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
@@ -0,0 +1,37 @@
+package policy
+
+import "time"
+
+// Rule represents a port policy rule.
+// A rule applies to every host when Tag is empty, otherwise only to hosts carrying the tag.
+// When AllowedPorts is set, any open port outside of it is a violation.
+// Any open port listed in DeniedPorts is a violation.
+type Rule struct {
+	RuleID       string    `db:"rule_id" json:"rule_id,omitempty"`
+	WorkspaceID  string    `db:"workspace_id" json:"workspace_id"`
+	Name         string    `db:"name" json:"name" validate:"required,max=255"`
+	Tag          string    `db:"tag" json:"tag,omitempty" validate:"max=255"`
+	AllowedPorts []int     `db:"allowed_ports" json:"allowed_ports,omitempty" validate:"dive,min=0,max=65535"`
+	DeniedPorts  []int     `db:"denied_ports" json:"denied_ports,omitempty" validate:"dive,min=0,max=65535"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// Violation represents an open port that breaks a policy rule
+type Violation struct {
+	ViolationID string     `db:"violation_id" json:"violation_id,omitempty"`
+	WorkspaceID string     `db:"workspace_id" json:"workspace_id"`
+	RuleID      string     `db:"rule_id" json:"rule_id"`
+	RuleName    string     `json:"rule_name"`
+	IPAddress   string     `db:"ip_address" json:"ip_address"`
+	Port        int        `db:"port" json:"port"`
+	Message     string     `db:"message" json:"message"`
+	Timestamp   time.Time  `db:"timestamp" json:"timestamp"`
+	ResolvedAt  *time.Time `db:"resolved_at" json:"resolved_at,omitempty"` // When a scan found the port no longer breaking the rule, open while nil
+}
+
+// ViolationFilter represents the filters that can be applied when listing violations
+type ViolationFilter struct {
+	WorkspaceID string `form:"-"`                                    // Workspace of the violations
+	IPAddress   string `form:"ip_address" validate:"omitempty,ip"`   // Only return violations for this IP address
+	RuleID      string `form:"rule_id" validate:"omitempty,numeric"` // Only return violations of this rule
+}
@@ -0,0 +1,151 @@
+package policy
+
+import (
+	"backend/internal/scan"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PolicyClient represents a client for evaluating port policy rules
+type PolicyClient struct {
+	Logger       *zap.Logger    // Logger
+	DBClient     *DBClient      // Policy database client
+	ScanDBClient *scan.DBClient // Scan database client, used to look up host tags
+}
+
+// NewPolicyClient creates a new PolicyClient
+func NewPolicyClient(logger *zap.Logger, DBClient *DBClient, scanDBClient *scan.DBClient) *PolicyClient {
+	return &PolicyClient{
+		Logger:       logger,
+		DBClient:     DBClient,
+		ScanDBClient: scanDBClient,
+	}
+}
+
+// EvaluateScan evaluates every policy rule against a scan response and records the violations found.
+// Only the violations the host didn't already have open are returned, the ones that went away are resolved.
+// A host that is down is left alone as its ports are unknown, as is an imported scan older than the last one of the host.
+func (p *PolicyClient) EvaluateScan(ctx context.Context, response *scan.ScanResponse) ([]*Violation, error) {
+	if response.HostStatus == scan.HostStatusDown || response.Historical {
+		return nil, nil
+	}
+
+	rules, err := p.DBClient.QueryRules(ctx, response.WorkspaceID)
+	if err != nil {
+		p.Logger.Error("error querying policy rules", zap.Error(err))
+		return nil, fmt.Errorf("error querying policy rules")
+	}
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	tags, err := p.ScanDBClient.QueryHostTags(ctx, response.WorkspaceID, response.Host.IPAddress)
+	if err != nil {
+		p.Logger.Error("error querying host tags", zap.Error(err))
+		return nil, fmt.Errorf("error querying tags for host %s", response.Host.IPAddress)
+	}
+
+	violations := evaluateRules(rules, tags, response.ScanResults)
+
+	p.Logger.Debug("Policy Violations", zap.Any("violations", violations))
+
+	resolvedAt := time.Now().UTC().Truncate(time.Second)
+	added, err := p.DBClient.RecordViolations(ctx, response.WorkspaceID, response.Host.IPAddress, violations, resolvedAt)
+	if err != nil {
+		p.Logger.Error("error recording policy violations", zap.Error(err))
+		return nil, fmt.Errorf("error storing policy violations for host %s", response.Host.IPAddress)
+	}
+
+	return added, nil
+}
+
+// diffViolations compares the violations found by a scan against the open violations of the host, matched by rule and port.
+// It returns the violations found that aren't open yet and the open violations that weren't found again.
+func diffViolations(open []*Violation, found []*Violation) (added []*Violation, resolved []*Violation) {
+	key := func(violation *Violation) string {
+		return violation.RuleID + ":" + strconv.Itoa(violation.Port)
+	}
+
+	openKeys := make(map[string]bool)
+	for _, violation := range open {
+		openKeys[key(violation)] = true
+	}
+	foundKeys := make(map[string]bool)
+	for _, violation := range found {
+		foundKeys[key(violation)] = true
+		if !openKeys[key(violation)] {
+			added = append(added, violation)
+		}
+	}
+
+	for _, violation := range open {
+		if !foundKeys[key(violation)] {
+			resolved = append(resolved, violation)
+		}
+	}
+
+	return added, resolved
+}
+
+// evaluateRules returns a violation for every open port that breaks one of the rules applying to a host with the given tags
+func evaluateRules(rules []*Rule, tags []string, scanResults []*scan.ScanResult) []*Violation {
+	tagSet := make(map[string]bool)
+	for _, tag := range tags {
+		tagSet[tag] = true
+	}
+
+	var violations []*Violation
+	for _, rule := range rules {
+		// Rules with a tag only apply to the hosts carrying it
+		if rule.Tag != "" && !tagSet[rule.Tag] {
+			continue
+		}
+
+		allowed := make(map[int]bool)
+		for _, port := range rule.AllowedPorts {
+			allowed[port] = true
+		}
+		denied := make(map[int]bool)
+		for _, port := range rule.DeniedPorts {
+			denied[port] = true
+		}
+
+		for _, result := range scanResults {
+			if result.Status != "open" {
+				continue
+			}
+
+			var message string
+			switch {
+			case denied[result.Port]:
+				message = fmt.Sprintf("port %d must never be open", result.Port)
+			case len(allowed) > 0 && !allowed[result.Port]:
+				message = fmt.Sprintf("port %d is not in the allowed ports %v", result.Port, rule.AllowedPorts)
+			default:
+				continue
+			}
+
+			timestamp := result.Timestamp
+			if timestamp.IsZero() {
+				timestamp = time.Now().UTC().Truncate(time.Second)
+			}
+
+			violations = append(violations, &Violation{
+				WorkspaceID: rule.WorkspaceID,
+				RuleID:      rule.RuleID,
+				RuleName:    rule.Name,
+				IPAddress:   result.IPAddress,
+				Port:        result.Port,
+				Message:     message,
+				Timestamp:   timestamp,
+			})
+		}
+	}
+
+	return violations
+}
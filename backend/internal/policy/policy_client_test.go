@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"backend/internal/scan"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_evaluateRules(t *testing.T) {
+	scanTime := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	webRule := &Rule{RuleID: "1", Name: "web hosts", Tag: "web", AllowedPorts: []int{80, 443}}
+	rdpRule := &Rule{RuleID: "2", Name: "no rdp", DeniedPorts: []int{3389}}
+
+	type args struct {
+		rules       []*Rule
+		tags        []string
+		scanResults []*scan.ScanResult
+	}
+	tests := []struct {
+		name string
+		args args
+		want []*Violation
+	}{
+		{
+			name: "Test Case 1: No Violations",
+			args: args{
+				rules: []*Rule{webRule, rdpRule},
+				tags:  []string{"web"},
+				scanResults: []*scan.ScanResult{
+					{IPAddress: "1234", Port: 80, Status: "open", Timestamp: scanTime},
+					{IPAddress: "1234", Port: 443, Status: "open", Timestamp: scanTime},
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "Test Case 2: Port Outside Allowed Ports",
+			args: args{
+				rules: []*Rule{webRule},
+				tags:  []string{"web"},
+				scanResults: []*scan.ScanResult{
+					{IPAddress: "1234", Port: 80, Status: "open", Timestamp: scanTime},
+					{IPAddress: "1234", Port: 22, Status: "open", Timestamp: scanTime},
+				},
+			},
+			want: []*Violation{
+				{RuleID: "1", RuleName: "web hosts", IPAddress: "1234", Port: 22, Message: "port 22 is not in the allowed ports [80 443]", Timestamp: scanTime},
+			},
+		},
+		{
+			name: "Test Case 3: Tagged Rule Ignored For Untagged Host",
+			args: args{
+				rules: []*Rule{webRule},
+				tags:  []string{"db"},
+				scanResults: []*scan.ScanResult{
+					{IPAddress: "1234", Port: 22, Status: "open", Timestamp: scanTime},
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "Test Case 4: Denied Port On Any Host",
+			args: args{
+				rules: []*Rule{rdpRule},
+				scanResults: []*scan.ScanResult{
+					{IPAddress: "1234", Port: 3389, Status: "open", Timestamp: scanTime},
+					{IPAddress: "1234", Port: 3390, Status: "open", Timestamp: scanTime},
+				},
+			},
+			want: []*Violation{
+				{RuleID: "2", RuleName: "no rdp", IPAddress: "1234", Port: 3389, Message: "port 3389 must never be open", Timestamp: scanTime},
+			},
+		},
+		{
+			name: "Test Case 5: Closed Ports Are Ignored",
+			args: args{
+				rules: []*Rule{rdpRule},
+				scanResults: []*scan.ScanResult{
+					{IPAddress: "1234", Port: 3389, Status: "closed", Timestamp: scanTime},
+				},
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, evaluateRules(tt.args.rules, tt.args.tags, tt.args.scanResults), "evaluateRules(%v, %v, %v)", tt.args.rules, tt.args.tags, tt.args.scanResults)
+		})
+	}
+}
+
+func Test_diffViolations(t *testing.T) {
+	open := []*Violation{
+		{ViolationID: "10", RuleID: "1", Port: 22},
+		{ViolationID: "11", RuleID: "2", Port: 3389},
+	}
+	found := []*Violation{
+		{RuleID: "1", IPAddress: "1234", Port: 22},
+		{RuleID: "1", IPAddress: "1234", Port: 8080},
+		{RuleID: "2", IPAddress: "1234", Port: 22},
+	}
+
+	added, resolved := diffViolations(open, found)
+	assert.Equal(t, []*Violation{found[1], found[2]}, added, "a violation already open shouldn't be recorded again")
+	assert.Equal(t, []*Violation{open[1]}, resolved)
+
+	added, resolved = diffViolations(open, found[:1])
+	assert.Empty(t, added, "an unchanged host shouldn't produce new violations")
+	assert.Equal(t, []*Violation{open[1]}, resolved)
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/policy"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) postPolicyHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var rule policy.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		s.Logger.Error("unable to bind json", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+
+	if err := validate.Struct(rule); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	// A rule without any ports can never be violated
+	if len(rule.AllowedPorts) == 0 && len(rule.DeniedPorts) == 0 {
+		abortWithError(c, apierror.Invalid("allowed_ports", "required_without", "At least one allowed or denied port is required"))
+		return
+	}
+
+	rule.WorkspaceID = getWorkspaceID(c)
+	if err := s.PolicyClient.DBClient.InsertRule(ctx, &rule); err != nil {
+		s.Logger.Error("unable to insert policy rule", zap.Error(err))
+		abortWithError(c, storeError("Unable to create policy rule", err))
+		return
+	}
+
+	setAuditTarget(c, rule.RuleID)
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (s *Server) getPoliciesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rules, err := s.PolicyClient.DBClient.QueryRules(ctx, getWorkspaceID(c))
+	if err != nil {
+		s.Logger.Error("unable to query policy rules", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query policy rules", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (s *Server) deletePolicyHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.PolicyClient.DBClient.DeleteRule(ctx, getWorkspaceID(c), c.Param("id"))
+	if errors.Is(err, policy.ErrRuleNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to delete policy rule", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to delete policy rule", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) getViolationsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var filter policy.ViolationFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		s.Logger.Error("unable to bind query", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+
+	if err := validate.Struct(filter); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	filter.WorkspaceID = getWorkspaceID(c)
+	violations, err := s.PolicyClient.DBClient.QueryViolations(ctx, filter)
+	if err != nil {
+		s.Logger.Error("unable to query policy violations", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query policy violations", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, violations)
+}
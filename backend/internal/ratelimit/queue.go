@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// QueueRetryAfter is the delay suggested to clients turned away by a full queue
+const QueueRetryAfter = 30 * time.Second
+
+// ErrQueueFull is returned when the wait queue of a Queue is full.
+var ErrQueueFull = errors.New("too many scans are queued, try again later")
+
+// Queue caps the number of jobs running at once, extra jobs wait in line until a slot frees up.
+// Jobs are turned away with ErrQueueFull once MaxWaiting jobs are already waiting.
+type Queue struct {
+	MaxWaiting int // Maximum number of jobs waiting for a slot
+
+	slots   chan struct{}
+	mu      sync.Mutex
+	waiting int
+}
+
+// NewQueue creates a Queue running at most maxRunning jobs at once with up to maxWaiting jobs waiting
+func NewQueue(maxRunning int, maxWaiting int) *Queue {
+	return &Queue{
+		MaxWaiting: maxWaiting,
+		slots:      make(chan struct{}, maxRunning),
+	}
+}
+
+// Acquire waits for a free slot, Release must be called once the job is done.
+// It fails with ErrQueueFull when the wait queue is full, or with the context error when the context ends first.
+func (q *Queue) Acquire(ctx context.Context) error {
+	// Take a free slot right away when there is one
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	q.mu.Lock()
+	if q.waiting >= q.MaxWaiting {
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+	q.waiting++
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		q.waiting--
+		q.mu.Unlock()
+	}()
+
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees the slot taken by Acquire
+func (q *Queue) Release() {
+	<-q.slots
+}
+
+// Running returns the number of jobs holding a slot
+func (q *Queue) Running() int {
+	return len(q.slots)
+}
+
+// Waiting returns the number of jobs waiting for a slot
+func (q *Queue) Waiting() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.waiting
+}
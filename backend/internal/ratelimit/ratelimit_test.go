@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(60, 2)
+	limiter.now = func() time.Time { return now }
+
+	// The burst is available right away
+	allowed, _ := limiter.Allow("key:1")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("key:1")
+	assert.True(t, allowed)
+
+	// The bucket is empty, the next token comes in a second
+	allowed, retryAfter := limiter.Allow("key:1")
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// Other keys have their own bucket
+	allowed, _ = limiter.Allow("key:2")
+	assert.True(t, allowed)
+
+	now = now.Add(time.Second)
+	allowed, _ = limiter.Allow("key:1")
+	assert.True(t, allowed)
+}
+
+func TestLimiterSweep(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(60, 2)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("key:1")
+	now = now.Add(2 * sweepInterval)
+	limiter.Allow("key:2")
+
+	assert.NotContains(t, limiter.buckets, "key:1")
+	assert.Contains(t, limiter.buckets, "key:2")
+}
+
+func TestQueue(t *testing.T) {
+	queue := NewQueue(1, 1)
+
+	assert.NoError(t, queue.Acquire(context.Background()))
+	assert.Equal(t, 1, queue.Running())
+
+	// The second job waits for the slot
+	acquired := make(chan error)
+	go func() { acquired <- queue.Acquire(context.Background()) }()
+	assert.Eventually(t, func() bool { return queue.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	// The wait queue is full
+	assert.ErrorIs(t, queue.Acquire(context.Background()), ErrQueueFull)
+
+	queue.Release()
+	assert.NoError(t, <-acquired)
+	assert.Equal(t, 0, queue.Waiting())
+	assert.Equal(t, 1, queue.Running())
+}
+
+func TestQueueContextCancelled(t *testing.T) {
+	queue := NewQueue(1, 1)
+	assert.NoError(t, queue.Acquire(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, queue.Acquire(ctx), context.Canceled)
+	assert.Equal(t, 0, queue.Waiting())
+}
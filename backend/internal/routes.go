@@ -1,22 +1,60 @@
 package internal
 
 import (
+	"backend/internal/apierror"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/dedup"
+	"backend/internal/email"
+	"backend/internal/metrics"
+	"backend/internal/policy"
+	"backend/internal/ratelimit"
 	"backend/internal/scan"
+	"backend/internal/schedule"
+	"backend/internal/scope"
+	"backend/internal/syslog"
+	"backend/internal/tracing"
+	"backend/internal/webhook"
+	"backend/internal/workspace"
+	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap"
 )
 
 type Server struct {
-	Router     *gin.Engine
-	Logger     *zap.Logger
-	ScanClient *scan.ScanClient
-	DBClient   *scan.DBClient
+	Router            *gin.Engine
+	Logger            *zap.Logger
+	ScanClient        *scan.ScanClient
+	DBClient          *scan.DBClient
+	PolicyClient      *policy.PolicyClient
+	WebhookClient     *webhook.WebhookClient
+	EmailClient       *email.EmailClient
+	SyslogClient      *syslog.SyslogClient
+	Scheduler         *schedule.Scheduler
+	Scope             *scope.Scope
+	AuthClient        *auth.AuthClient
+	JWTVerifier       *auth.JWTVerifier
+	WorkspaceDBClient workspace.IDBClient
+	AuditDBClient     audit.IDBClient
+	KeyLimiter        *ratelimit.Limiter
+	IPLimiter         *ratelimit.Limiter
+	ScanGroup         *dedup.Group[*ScanResponse]
 }
 
 func NewServer(router *gin.Engine) *Server {
+	// Forwarded headers are ignored until trusted proxies are configured, any caller could set them
+	_ = router.SetTrustedProxies(nil)
+
 	return &Server{
 		Router: router,
 	}
@@ -29,7 +67,84 @@ func (s *Server) Run(port string) error {
 }
 
 func (s *Server) Routes() {
-	s.Router.POST("/scan", s.postScanPortsHandler)
+	s.Router.Use(s.requestID, s.accessLog, s.recoverPanic)
+
+	// Probes and scrapes aren't worth a trace
+	s.Router.Use(otelgin.Middleware(tracing.DefaultServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/metrics" && r.URL.Path != "/healthz" && r.URL.Path != "/readyz"
+	})))
+	s.Router.Use(s.observeRequest)
+
+	s.Router.NoRoute(func(c *gin.Context) {
+		abortWithError(c, apierror.New(apierror.CodeNotFound, "Route "+c.Request.Method+" "+c.Request.URL.Path+" not found"))
+	})
+
+	// Metrics are scraped without credentials, keep the port away from untrusted networks
+	s.Router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Probes are unauthenticated so the orchestrator can call them
+	s.Router.GET("/healthz", s.getHealthzHandler)
+	s.Router.GET("/readyz", s.getReadyzHandler)
+
+	// Every route requires credentials, the scopes of the caller decide what it may do
+	authenticated := s.Router.Group("/", s.authenticate)
+	authenticated.GET("/workspaces", s.getWorkspacesHandler)
+
+	// API keys, workspaces and the audit log are global, everything else belongs to the workspace of the request
+	global := authenticated.Group("/", s.requireScope(auth.ScopeAdmin))
+	global.POST("/keys", s.audit(audit.ActionKeyCreate), s.postKeyHandler)
+	global.GET("/keys", s.getKeysHandler)
+	global.DELETE("/keys/:id", s.audit(audit.ActionKeyRevoke), s.deleteKeyHandler)
+
+	global.POST("/workspaces", s.audit(audit.ActionWorkspaceCreate), s.postWorkspaceHandler)
+	global.POST("/workspaces/:id/members", s.audit(audit.ActionMemberAdd), s.postWorkspaceMemberHandler)
+	global.GET("/workspaces/:id/members", s.getWorkspaceMembersHandler)
+	global.DELETE("/workspaces/:id/members/:principal", s.audit(audit.ActionMemberRemove), s.deleteWorkspaceMemberHandler)
+
+	global.GET("/audit", s.getAuditHandler)
+
+	workspaced := authenticated.Group("/", s.resolveWorkspace)
+
+	scans := workspaced.Group("/", s.requireScope(auth.ScopeScanRun))
+	scans.POST("/scan", s.audit(audit.ActionScanStart), s.rateLimit, s.postScanPortsHandler)
+	scans.POST("/imports/nmap-xml", s.audit(audit.ActionScanImport), s.postReportImportHandler(scan.ReportNmapXML))
+	scans.POST("/imports/masscan-json", s.audit(audit.ActionScanImport), s.postReportImportHandler(scan.ReportMasscanJSON))
+	scans.POST("/imports/masscan-list", s.audit(audit.ActionScanImport), s.postReportImportHandler(scan.ReportMasscanList))
+	scans.POST("/imports/naabu-jsonl", s.audit(audit.ActionScanImport), s.postReportImportHandler(scan.ReportNaabuJSONL))
+	scans.POST("/imports/targets", s.audit(audit.ActionHostsImport), s.postTargetImportHandler)
+
+	history := workspaced.Group("/", s.requireScope(auth.ScopeHistoryRead))
+	history.GET("/scan-runs", s.getScanRunsHandler)
+	history.GET("/hosts/:ip/tags", s.getHostTagsHandler)
+	history.GET("/hosts/:ip/availability", s.getHostAvailabilityHandler)
+	history.GET("/hosts/:ip/names", s.getHostNamesHandler)
+	history.GET("/policies", s.getPoliciesHandler)
+	history.GET("/violations", s.getViolationsHandler)
+
+	// The admin scope is global, workspace settings are managed by the admins of the workspace
+	admin := workspaced.Group("/", s.requireWorkspaceAdmin)
+	admin.PUT("/hosts/:ip/tags", s.audit(audit.ActionHostTagsUpdate), s.putHostTagsHandler)
+
+	admin.POST("/policies", s.audit(audit.ActionPolicyCreate), s.postPolicyHandler)
+	admin.DELETE("/policies/:id", s.audit(audit.ActionPolicyDelete), s.deletePolicyHandler)
+
+	admin.POST("/webhooks", s.audit(audit.ActionWebhookCreate), s.postWebhookHandler)
+	admin.GET("/webhooks", s.getWebhooksHandler)
+	admin.DELETE("/webhooks/:id", s.audit(audit.ActionWebhookDelete), s.deleteWebhookHandler)
+	admin.GET("/webhooks/:id/deliveries", s.getWebhookDeliveriesHandler)
+
+	admin.POST("/schedules", s.audit(audit.ActionScheduleCreate), s.postScheduleHandler)
+	admin.GET("/schedules", s.getSchedulesHandler)
+	admin.GET("/schedules/:id", s.getScheduleHandler)
+	admin.PUT("/schedules/:id", s.audit(audit.ActionScheduleUpdate), s.putScheduleHandler)
+	admin.DELETE("/schedules/:id", s.audit(audit.ActionScheduleDelete), s.deleteScheduleHandler)
+
+	// Email alerts are only available when an SMTP server is configured
+	if s.EmailClient != nil {
+		admin.POST("/email-subscribers", s.audit(audit.ActionSubscriberCreate), s.postEmailSubscriberHandler)
+		admin.GET("/email-subscribers", s.getEmailSubscribersHandler)
+		admin.DELETE("/email-subscribers/:id", s.audit(audit.ActionSubscriberDelete), s.deleteEmailSubscriberHandler)
+	}
 }
 
 func (s *Server) bootstrapDependencies() {
@@ -46,6 +161,23 @@ func (s *Server) bootstrapDependencies() {
 
 	s.Logger.Info("bootstrapping dependencies")
 
+	if proxies := splitEnv("TRUSTED_PROXIES"); len(proxies) > 0 {
+		if err := s.Router.SetTrustedProxies(proxies); err != nil {
+			panic(fmt.Sprintf("invalid trusted proxies: %s", err.Error()))
+		}
+	}
+
+	OTLPEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if OTLPEndpoint != "" {
+		config := tracing.Config{
+			Endpoint:    OTLPEndpoint,
+			ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		}
+		if _, err := tracing.Setup(context.Background(), config); err != nil {
+			panic(fmt.Sprintf("error configuring tracing: %s", err.Error()))
+		}
+	}
+
 	DBUsername := os.Getenv("DB_USERNAME")
 	if DBUsername == "" {
 		panic("DB_USERNAME is not set")
@@ -72,5 +204,204 @@ func (s *Server) bootstrapDependencies() {
 	s.DBClient = scan.NewDBClient(connectionString, s.Logger)
 
 	s.ScanClient = scan.NewScanClient(s.Logger, s.DBClient)
+	s.ScanClient.Timeout = 10 * time.Minute
+	if value := os.Getenv("SCAN_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			panic("SCAN_TIMEOUT must be a positive duration")
+		}
+		s.ScanClient.Timeout = timeout
+	}
+	s.bootstrapRateLimits()
+
+	scanCooldown := time.Minute
+	if value := os.Getenv("SCAN_COOLDOWN"); value != "" {
+		cooldown, err := time.ParseDuration(value)
+		if err != nil || cooldown < 0 {
+			panic("SCAN_COOLDOWN must be a positive duration")
+		}
+		scanCooldown = cooldown
+	}
+	s.ScanGroup = dedup.NewGroup[*ScanResponse](scanCooldown)
+
+	s.WorkspaceDBClient = workspace.NewDBClient(s.DBClient.DB, s.Logger)
+
+	s.AuditDBClient = audit.NewDBClient(s.DBClient.DB, s.Logger)
+
+	s.AuthClient = auth.NewAuthClient(s.Logger, auth.NewDBClient(s.DBClient.DB, s.Logger), os.Getenv("ADMIN_API_KEY"))
+
+	OIDCIssuer := os.Getenv("OIDC_ISSUER")
+	if OIDCIssuer != "" {
+		s.bootstrapJWT(OIDCIssuer)
+	}
+
+	var err error
+	s.Scope, err = scope.NewScope(splitEnv("SCOPE_ALLOWED_CIDRS"), splitEnv("SCOPE_ALLOWED_DOMAINS"), splitEnv("SCOPE_DENIED_CIDRS"))
+	if err != nil {
+		panic(fmt.Sprintf("invalid scan scope: %s", err.Error()))
+	}
+
+	s.PolicyClient = policy.NewPolicyClient(s.Logger, policy.NewDBClient(s.DBClient.DB, s.Logger), s.DBClient)
+
+	s.WebhookClient = webhook.NewWebhookClient(s.Logger, webhook.NewDBClient(s.DBClient.DB, s.Logger))
+	for _, value := range splitEnv("WEBHOOK_ALLOWED_CIDRS") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			panic(fmt.Sprintf("invalid WEBHOOK_ALLOWED_CIDRS: %s", err.Error()))
+		}
+		s.WebhookClient.AllowedNetworks = append(s.WebhookClient.AllowedNetworks, network)
+	}
+
+	SMTPHost := os.Getenv("SMTP_HOST")
+	if SMTPHost != "" {
+		s.bootstrapEmail(SMTPHost)
+	}
+
+	SyslogAddress := os.Getenv("SYSLOG_ADDRESS")
+	if SyslogAddress != "" {
+		s.bootstrapSyslog(SyslogAddress)
+	}
+
+	s.Scheduler = schedule.NewScheduler(s.Logger, schedule.NewDBClient(s.DBClient.DB, s.Logger), s.runSchedule)
+	if value := os.Getenv("SCHEDULER_MAX_JITTER"); value != "" {
+		maxJitter, err := time.ParseDuration(value)
+		if err != nil || maxJitter < 0 {
+			panic("SCHEDULER_MAX_JITTER must be a positive duration")
+		}
+		s.Scheduler.MaxJitter = maxJitter
+	}
 
+	go s.Scheduler.Start(context.Background())
+
+}
+
+// bootstrapEmail configures email alerts through the given SMTP server and starts the daily digest
+func (s *Server) bootstrapEmail(SMTPHost string) {
+	SMTPFrom := os.Getenv("SMTP_FROM")
+	if SMTPFrom == "" {
+		panic("SMTP_FROM is not set")
+	}
+	SMTPPort := os.Getenv("SMTP_PORT")
+	if SMTPPort == "" {
+		SMTPPort = "25"
+	}
+
+	digestHour := 8
+	if value := os.Getenv("EMAIL_DIGEST_HOUR"); value != "" {
+		hour, err := strconv.Atoi(value)
+		if err != nil || hour < 0 || hour > 23 {
+			panic("EMAIL_DIGEST_HOUR must be an hour between 0 and 23")
+		}
+		digestHour = hour
+	}
+
+	config := email.Config{
+		Host:       SMTPHost,
+		Port:       SMTPPort,
+		Username:   os.Getenv("SMTP_USERNAME"),
+		Password:   os.Getenv("SMTP_PASSWORD"),
+		From:       SMTPFrom,
+		DigestHour: digestHour,
+	}
+
+	s.EmailClient = email.NewEmailClient(s.Logger, email.NewDBClient(s.DBClient.DB, s.Logger), config)
+
+	go s.EmailClient.RunDigest(context.Background())
+}
+
+// bootstrapSyslog configures the syslog event output to the given receiver
+func (s *Server) bootstrapSyslog(SyslogAddress string) {
+	SyslogNetwork := os.Getenv("SYSLOG_NETWORK")
+	if SyslogNetwork == "" {
+		SyslogNetwork = "udp"
+	}
+	if SyslogNetwork != "udp" && SyslogNetwork != "tcp" {
+		panic("SYSLOG_NETWORK must be udp or tcp")
+	}
+
+	SyslogFormat := os.Getenv("SYSLOG_FORMAT")
+	if SyslogFormat != "" && SyslogFormat != "rfc5424" && SyslogFormat != "cef" {
+		panic("SYSLOG_FORMAT must be rfc5424 or cef")
+	}
+
+	SyslogEnterpriseNumber := os.Getenv("SYSLOG_ENTERPRISE_NUMBER")
+	if SyslogEnterpriseNumber != "" && !syslog.ValidEnterpriseNumber(SyslogEnterpriseNumber) {
+		panic("SYSLOG_ENTERPRISE_NUMBER must be a private enterprise number")
+	}
+
+	hostname, _ := os.Hostname()
+
+	config := syslog.Config{
+		Network:          SyslogNetwork,
+		Address:          SyslogAddress,
+		AppName:          "nmap_project",
+		Hostname:         hostname,
+		CEF:              SyslogFormat == "cef",
+		EnterpriseNumber: SyslogEnterpriseNumber,
+	}
+
+	s.SyslogClient = syslog.NewSyslogClient(s.Logger, config)
+}
+
+// bootstrapRateLimits configures the per caller scan rate limits and the cap on concurrent nmap processes
+func (s *Server) bootstrapRateLimits() {
+	burst := intEnv("RATE_LIMIT_BURST", 5)
+	s.KeyLimiter = ratelimit.NewLimiter(float64(intEnv("RATE_LIMIT_PER_KEY", 10)), burst)
+	s.IPLimiter = ratelimit.NewLimiter(float64(intEnv("RATE_LIMIT_PER_IP", 30)), burst)
+
+	s.ScanClient.Queue = ratelimit.NewQueue(intEnv("NMAP_MAX_CONCURRENT", 4), intEnv("NMAP_MAX_QUEUE", 20))
+	metrics.RegisterQueue(s.ScanClient.Queue.Running, s.ScanClient.Queue.Waiting)
+}
+
+// intEnv returns the positive integer value of an environment variable, or the fallback when unset
+func intEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		panic(key + " must be a positive integer")
+	}
+	return number
+}
+
+// splitEnv returns the comma separated values of an environment variable
+func splitEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// bootstrapJWT configures bearer token authentication against the given issuer
+func (s *Server) bootstrapJWT(OIDCIssuer string) {
+	// Roles are mapped to scopes as role=scope|scope,role=scope
+	roleScopes := make(map[string][]string)
+	for _, mapping := range splitEnv("OIDC_ROLE_SCOPES") {
+		role, scopes, ok := strings.Cut(mapping, "=")
+		if !ok {
+			panic("OIDC_ROLE_SCOPES must be formatted as role=scope|scope,role=scope")
+		}
+		roleScopes[strings.TrimSpace(role)] = strings.Split(strings.TrimSpace(scopes), "|")
+	}
+
+	config := auth.JWTConfig{
+		Issuer:     OIDCIssuer,
+		Audience:   os.Getenv("OIDC_AUDIENCE"),
+		JWKSURL:    os.Getenv("OIDC_JWKS_URL"),
+		JWKSFile:   os.Getenv("OIDC_JWKS_FILE"),
+		RolesClaim: os.Getenv("OIDC_ROLES_CLAIM"),
+		RoleScopes: roleScopes,
+	}
+
+	var err error
+	s.JWTVerifier, err = auth.NewJWTVerifier(s.Logger, config)
+	if err != nil {
+		panic(fmt.Sprintf("error configuring bearer token authentication: %s", err.Error()))
+	}
 }
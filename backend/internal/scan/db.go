@@ -1,21 +1,40 @@
 package scan
 
 import (
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/tracing"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // IDBClient is an interface that defines the methods for interacting with the database.
 type IDBClient interface {
-	QueryPortHistory(ctx context.Context, ipAddress string, scans []*ScanResult) ([]*ScanResult, error)
-	UpsertScanResults(ctx context.Context, host Host, scanResults []*ScanResult) error
+	QueryPortHistory(ctx context.Context, workspaceID string, ipAddress string, scans []*ScanResult) ([]*ScanResult, error)
+	QueryLastScanResults(ctx context.Context, workspaceID string, ipAddress string, profile string) ([]*ScanResult, error)
+	QueryLastHostStatus(ctx context.Context, workspaceID string, ipAddress string) (string, error)
+	QueryHostNames(ctx context.Context, workspaceID string, ipAddress string) ([]*HostName, error)
+	UpsertHostNames(ctx context.Context, workspaceID string, ipAddress string, names []*HostName) error
+	UpsertScanResults(ctx context.Context, workspaceID string, host Host, run *ScanRun, scanResults []*ScanResult) error
+	QueryScanRuns(ctx context.Context, workspaceID string, ipAddress string) ([]*ScanRun, error)
+	QueryHostTags(ctx context.Context, workspaceID string, ipAddress string) ([]string, error)
+	UpdateHostTags(ctx context.Context, workspaceID string, ipAddress string, tags []string) error
+	ImportHost(ctx context.Context, workspaceID string, host Host, tags []string) error
 }
 
+// ErrHostNotFound is returned when a host has never been scanned.
+var ErrHostNotFound = errors.New("host not found")
+
 // DBClient is a struct that implements the IDBClient interface.
 type DBClient struct {
 	DB     *sql.DB
@@ -41,9 +60,14 @@ func NewDBClient(connectionString string, logger *zap.Logger) *DBClient {
 	}
 }
 
-// hostExists checks if an ip address exists in the database's Hosts table
-func (db *DBClient) hostExists(ctx context.Context, ipAddress string) (bool, error) {
-	res, err := db.DB.QueryContext(ctx, `SELECT ip_address FROM Hosts WHERE ip_address = ?`, ipAddress)
+// queryer runs queries on the database or inside a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// hostExists checks if an ip address exists in a workspace in the database's Hosts table
+func hostExists(ctx context.Context, q queryer, workspaceID string, ipAddress string) (bool, error) {
+	res, err := q.QueryContext(ctx, `SELECT ip_address FROM Hosts WHERE workspace_id = ? AND ip_address = ?`, workspaceID, ipAddress)
 	if err != nil {
 		return false, err
 	}
@@ -57,8 +81,14 @@ func (db *DBClient) hostExists(ctx context.Context, ipAddress string) (bool, err
 	return false, nil
 }
 
-// QueryPortHistory queries the database for the port history of a given ports for a given IP address.
-func (db *DBClient) QueryPortHistory(ctx context.Context, ipAddress string, scans []*ScanResult) ([]*ScanResult, error) {
+// QueryPortHistory queries the database for the port history of a given ports for a given IP address in a workspace.
+func (db *DBClient) QueryPortHistory(ctx context.Context, workspaceID string, ipAddress string, scans []*ScanResult) (_ []*ScanResult, err error) {
+	defer metrics.ObserveDBQuery("query_port_history", time.Now())
+
+	ctx, span := tracer.Start(ctx, "DBClient.QueryPortHistory", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMySQL, attribute.String("host.ip_address", ipAddress)))
+	defer func() { tracing.End(span, err) }()
+
 	// Start a transaction
 	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -66,7 +96,7 @@ func (db *DBClient) QueryPortHistory(ctx context.Context, ipAddress string, scan
 	}
 
 	// Query the database for all the scan results for the given IP address as the ip_address column of the scan_results table
-	rows, err := tx.QueryContext(ctx, `SELECT * FROM ScanResults WHERE ip_address = ?`, ipAddress)
+	rows, err := tx.QueryContext(ctx, `SELECT scan_id, ip_address, port, timestamp, status FROM ScanResults WHERE workspace_id = ? AND ip_address = ?`, workspaceID, ipAddress)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -103,36 +133,322 @@ func (db *DBClient) QueryPortHistory(ctx context.Context, ipAddress string, scan
 		return nil, err
 	}
 
+	logging.FromContext(ctx, db.Logger).Debug("queried port history", zap.String("ip_address", ipAddress), zap.Int("results", len(matchedPorts)))
+
 	return matchedPorts, nil
 }
 
-// UpsertScanResults inserts scan results in the database.
-func (db *DBClient) UpsertScanResults(ctx context.Context, host Host, scanResults []*ScanResult) error {
+// QueryLastScanResults queries the database for the open ports found by the newest scan run of a given IP address with a given profile
+// in a workspace, newest by the time the host was scanned as imported runs can be older than the ones recorded before them.
+// Runs during which the host was down are skipped, as they don't tell which ports are open.
+func (db *DBClient) QueryLastScanResults(ctx context.Context, workspaceID string, ipAddress string, profile string) ([]*ScanResult, error) {
+	defer metrics.ObserveDBQuery("query_last_scan_results", time.Now())
+
+	rows, err := db.DB.QueryContext(ctx, `SELECT scan_id, ip_address, port, timestamp, status FROM ScanResults WHERE scan_run_id = (
+		SELECT scan_run_id FROM ScanRuns WHERE workspace_id = ? AND ip_address = ? AND profile = ? AND host_status = ? ORDER BY timestamp DESC, scan_run_id DESC LIMIT 1
+	) ORDER BY port`, workspaceID, ipAddress, profile, HostStatusUp)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var results []*ScanResult
+	for rows.Next() {
+		var result ScanResult
+		var timestampStr string
+		if err := rows.Scan(&result.ScanID, &result.IPAddress, &result.Port, &timestampStr, &result.Status); err != nil {
+			return nil, err
+		}
+
+		result.Timestamp, err = time.Parse("2006-01-02 15:04:05", timestampStr)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, &result)
+	}
+
+	return results, rows.Err()
+}
+
+// QueryLastHostStatus queries the database for the host status found by the newest scan run of a given IP address in a workspace.
+// It returns an empty status when the host has never been scanned.
+func (db *DBClient) QueryLastHostStatus(ctx context.Context, workspaceID string, ipAddress string) (string, error) {
+	defer metrics.ObserveDBQuery("query_last_host_status", time.Now())
+
+	var status string
+	err := db.DB.QueryRowContext(ctx, `SELECT host_status FROM ScanRuns WHERE workspace_id = ? AND ip_address = ? ORDER BY timestamp DESC, scan_run_id DESC LIMIT 1`, workspaceID, ipAddress).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return status, err
+}
+
+// QueryLastScanTime queries the database for the time of the newest scan run of a given IP address in a workspace, with any profile.
+// It returns the zero time when the host has never been scanned.
+func (db *DBClient) QueryLastScanTime(ctx context.Context, workspaceID string, ipAddress string) (time.Time, error) {
+	defer metrics.ObserveDBQuery("query_last_scan_time", time.Now())
+
+	var timestampStr sql.NullString
+	err := db.DB.QueryRowContext(ctx, `SELECT MAX(timestamp) FROM ScanRuns WHERE workspace_id = ? AND ip_address = ?`, workspaceID, ipAddress).Scan(&timestampStr)
+	if err != nil || !timestampStr.Valid {
+		return time.Time{}, err
+	}
+
+	return time.Parse("2006-01-02 15:04:05", timestampStr.String)
+}
+
+// UpsertScanResults inserts a scan run and its results in a workspace in the database and sets the ID of the run.
+func (db *DBClient) UpsertScanResults(ctx context.Context, workspaceID string, host Host, run *ScanRun, scanResults []*ScanResult) (err error) {
+	defer metrics.ObserveDBQuery("upsert_scan_results", time.Now())
+
+	ctx, span := tracer.Start(ctx, "DBClient.UpsertScanResults", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMySQL, attribute.String("host.ip_address", host.IPAddress), attribute.Int("scan.results", len(scanResults))))
+	defer func() { tracing.End(span, err) }()
+
 	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	// Check if the host exists in the database
-	hostExists, err := db.hostExists(ctx, host.IPAddress)
+	exists, err := hostExists(ctx, tx, workspaceID, host.IPAddress)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
 	// If the host doesn't exist in the database, we need to insert it
-	if !hostExists {
-		queryString := `INSERT INTO Hosts (ip_address, hostname) VALUES (?, ?)`
-		_, err = tx.ExecContext(ctx, queryString, host.IPAddress, host.Hostname)
+	if !exists {
+		queryString := `INSERT INTO Hosts (workspace_id, ip_address, hostname) VALUES (?, ?, ?)`
+		_, err = tx.ExecContext(ctx, queryString, workspaceID, host.IPAddress, host.Hostname)
 		if err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
+	// Insert the scan run
+	queryString := `INSERT INTO ScanRuns (workspace_id, ip_address, hostname, profile, initiated_by, host_status, host_reason, source, scanner, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := tx.ExecContext(ctx, queryString, workspaceID, host.IPAddress, host.Hostname, run.Profile, run.InitiatedBy, run.HostStatus, run.HostReason, run.Source, run.Scanner, run.Timestamp)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	scanRunID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	run.ScanRunID = strconv.FormatInt(scanRunID, 10)
+	run.IPAddress = host.IPAddress
+	run.Hostname = host.Hostname
+
 	// Insert the scan results
 	for _, scan := range scanResults {
-		queryString := `INSERT INTO ScanResults (ip_address, port, timestamp, status) VALUES (?, ?, ?, ?)`
-		_, err = tx.ExecContext(ctx, queryString, host.IPAddress, scan.Port, scan.Timestamp, scan.Status)
+		queryString := `INSERT INTO ScanResults (workspace_id, ip_address, port, timestamp, status, scan_run_id) VALUES (?, ?, ?, ?, ?, ?)`
+		_, err = tx.ExecContext(ctx, queryString, workspaceID, host.IPAddress, scan.Port, scan.Timestamp, scan.Status, scanRunID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, db.Logger).Debug("inserted scan run", zap.String("scan_run_id", run.ScanRunID), zap.Int("results", len(scanResults)))
+
+	return nil
+}
+
+// QueryHostNames queries the database for every name ever reported for a given IP address in a workspace, most recently seen first.
+func (db *DBClient) QueryHostNames(ctx context.Context, workspaceID string, ipAddress string) ([]*HostName, error) {
+	defer metrics.ObserveDBQuery("query_host_names", time.Now())
+
+	rows, err := db.DB.QueryContext(ctx, `SELECT name, type, first_seen, last_seen, current FROM HostNames WHERE workspace_id = ? AND ip_address = ? ORDER BY last_seen DESC, name`, workspaceID, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	names := []*HostName{}
+	for rows.Next() {
+		var name HostName
+		var firstSeenStr, lastSeenStr string
+		if err := rows.Scan(&name.Name, &name.Type, &firstSeenStr, &lastSeenStr, &name.Current); err != nil {
+			return nil, err
+		}
+
+		if name.FirstSeen, err = time.Parse("2006-01-02 15:04:05", firstSeenStr); err != nil {
+			return nil, err
+		}
+		if name.LastSeen, err = time.Parse("2006-01-02 15:04:05", lastSeenStr); err != nil {
+			return nil, err
+		}
+
+		names = append(names, &name)
+	}
+
+	return names, rows.Err()
+}
+
+// UpsertHostNames records the names reported by the newest scan that listed a given IP address in a workspace.
+// Names seen before keep their first seen time, names that weren't reported anymore are no longer current.
+func (db *DBClient) UpsertHostNames(ctx context.Context, workspaceID string, ipAddress string, names []*HostName) error {
+	defer metrics.ObserveDBQuery("upsert_host_names", time.Now())
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE HostNames SET current = false WHERE workspace_id = ? AND ip_address = ?`, workspaceID, ipAddress)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, name := range names {
+		queryString := `INSERT INTO HostNames (workspace_id, ip_address, name, type, first_seen, last_seen, current) VALUES (?, ?, ?, ?, ?, ?, true)
+			ON DUPLICATE KEY UPDATE last_seen = VALUES(last_seen), current = true`
+		_, err = tx.ExecContext(ctx, queryString, workspaceID, ipAddress, name.Name, name.Type, name.FirstSeen, name.LastSeen)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
+
+// QueryScanRuns queries the database for the scan runs of a given IP address, or of every host when empty, in a workspace, newest first.
+func (db *DBClient) QueryScanRuns(ctx context.Context, workspaceID string, ipAddress string) ([]*ScanRun, error) {
+	defer metrics.ObserveDBQuery("query_scan_runs", time.Now())
+
+	queryString := `SELECT scan_run_id, ip_address, hostname, profile, initiated_by, host_status, host_reason, source, scanner, timestamp FROM ScanRuns WHERE workspace_id = ?`
+	args := []any{workspaceID}
+	if ipAddress != "" {
+		queryString += ` AND ip_address = ?`
+		args = append(args, ipAddress)
+	}
+	queryString += ` ORDER BY scan_run_id DESC`
+
+	rows, err := db.DB.QueryContext(ctx, queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	runs := []*ScanRun{}
+	for rows.Next() {
+		var run ScanRun
+		var timestampStr string
+		err := rows.Scan(&run.ScanRunID, &run.IPAddress, &run.Hostname, &run.Profile, &run.InitiatedBy, &run.HostStatus, &run.HostReason, &run.Source, &run.Scanner, &timestampStr)
+		if err != nil {
+			return nil, err
+		}
+
+		run.Timestamp, err = time.Parse("2006-01-02 15:04:05", timestampStr)
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, &run)
+	}
+
+	return runs, rows.Err()
+}
+
+// QueryHostTags queries the database for the tags assigned to a given IP address in a workspace.
+func (db *DBClient) QueryHostTags(ctx context.Context, workspaceID string, ipAddress string) ([]string, error) {
+	defer metrics.ObserveDBQuery("query_host_tags", time.Now())
+
+	rows, err := db.DB.QueryContext(ctx, `SELECT tag FROM HostTags WHERE workspace_id = ? AND ip_address = ? ORDER BY tag`, workspaceID, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// UpdateHostTags replaces the tags assigned to a given IP address in a workspace.
+func (db *DBClient) UpdateHostTags(ctx context.Context, workspaceID string, ipAddress string, tags []string) error {
+	defer metrics.ObserveDBQuery("update_host_tags", time.Now())
+
+	// Tags can only be assigned to hosts that have been scanned
+	exists, err := hostExists(ctx, db.DB, workspaceID, ipAddress)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrHostNotFound
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Remove the existing tags before inserting the new ones
+	_, err = tx.ExecContext(ctx, `DELETE FROM HostTags WHERE workspace_id = ? AND ip_address = ?`, workspaceID, ipAddress)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.ExecContext(ctx, `INSERT INTO HostTags (workspace_id, ip_address, tag) VALUES (?, ?, ?)`, workspaceID, ipAddress, tag)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
+
+// ImportHost adds a host to the inventory of a workspace along with tags. A host that is already known keeps its tags,
+// the new ones are added, and its hostname and owner are only replaced when the import sets them.
+func (db *DBClient) ImportHost(ctx context.Context, workspaceID string, host Host, tags []string) error {
+	defer metrics.ObserveDBQuery("import_host", time.Now())
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	queryString := `INSERT INTO Hosts (workspace_id, ip_address, hostname, owner) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE hostname = IF(VALUES(hostname) = '', hostname, VALUES(hostname)), owner = IF(VALUES(owner) = '', owner, VALUES(owner))`
+	_, err = tx.ExecContext(ctx, queryString, workspaceID, host.IPAddress, host.Hostname, host.Owner)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.ExecContext(ctx, `INSERT IGNORE INTO HostTags (workspace_id, ip_address, tag) VALUES (?, ?, ?)`, workspaceID, host.IPAddress, tag)
 		if err != nil {
 			tx.Rollback()
 			return err
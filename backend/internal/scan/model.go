@@ -1,16 +1,84 @@
 package scan
 
-import "time"
+import (
+	"encoding/xml"
+	"sort"
+	"time"
+)
+
+// Profiles maps the name of a scan profile to the nmap options it runs with
+var Profiles = map[string][]string{
+	DefaultProfile: {"-p", "0-1000", "--open", "-T5"},
+	"quick":        {"-F", "--open", "-T4"},
+	"full":         {"-p-", "--open", "-T4"},
+}
+
+// DefaultProfile is the profile used when a request doesn't specify one
+const DefaultProfile = "default"
+
+// ImportProfile is the profile of imported scans whose ports aren't known to match one of the profiles
+const ImportProfile = "import"
+
+// Sources of a scan run
+const (
+	SourceScan   = "scan"   // The scan was run by the service
+	SourceImport = "import" // The scan was run elsewhere and its report uploaded
+)
+
+// Scanners that can produce a scan run
+const (
+	ScannerNmap    = "nmap"
+	ScannerMasscan = "masscan"
+	ScannerNaabu   = "naabu"
+)
+
+// Formats of the scan reports that can be imported
+const (
+	ReportNmapXML     = "nmap-xml"     // nmap -oX
+	ReportMasscanJSON = "masscan-json" // masscan -oJ
+	ReportMasscanList = "masscan-list" // masscan -oL
+	ReportNaabuJSONL  = "naabu-jsonl"  // naabu -json, one JSON object per line
+)
+
+// Host statuses of a scan run
+const (
+	HostStatusUp   = "up"   // The host responded, even if none of its ports are open
+	HostStatusDown = "down" // The host didn't respond, its ports are unknown
+)
+
+// Types of the names of a host, as reported by nmap
+const (
+	HostNameTypeUser = "user" // Name the scan was requested for
+	HostNameTypePTR  = "PTR"  // Name found by reverse DNS
+)
+
+// Reverse DNS changes between two scans of a host
+const (
+	NameChangePTRAdded   = "ptr_added"   // The PTR record wasn't returned during the last scan of the host
+	NameChangePTRRemoved = "ptr_removed" // The PTR record returned during the last scan of the host is gone
+)
+
+// Host status changes between two scans of a host
+const (
+	HostChangeDown = "host_down" // The host was up during its last scan and is down now
+	HostChangeUp   = "host_up"   // The host was down during its last scan and came back
+)
 
 // ScanRequest represents a request to scan a list of IPs or hostnames
 type ScanRequest struct {
-	IPsOrHostnames []string `json:"ips_or_hostnames" validate:"required"` // List of IPs or hostnames to scan
+	IPsOrHostnames []string `json:"ips_or_hostnames" validate:"required,min=1,dive,ip|fqdn"`         // List of IPs or hostnames to scan
+	Profile        string   `json:"profile,omitempty" validate:"omitempty,oneof=default quick full"` // Scan profile, defaults to DefaultProfile
 }
 
 // ScanRequestMapped represents a mapped version of ScanRequest
 type ScanRequestMapped struct {
-	IPs       []string `validate:"dive,ip"`   // List of IPs to scan
-	Hostnames []string `validate:"dive,fqdn"` // List of hostnames to scan
+	IPs         []string `validate:"dive,ip"`                            // List of IPs to scan
+	Hostnames   []string `validate:"dive,fqdn"`                          // List of hostnames to scan
+	Profile     string   `validate:"omitempty,oneof=default quick full"` // Scan profile, defaults to DefaultProfile
+	InitiatedBy string   // Identity of whoever launched the scan
+	WorkspaceID string   // Workspace the scan belongs to
+	// Addresses of each hostname as resolved when the scope was checked, the hostname is scanned at these addresses
+	Addresses map[string][]string
 }
 
 // NMapScanPorts represents the ports to scan with NMap
@@ -20,19 +88,92 @@ type NMapScanPorts struct {
 
 // NmapRun represents the output of an NMap scan
 type NmapRun struct {
-	Start string     `xml:"start,attr"` // Start time of the scan
-	Hosts []NmapHost `xml:"host"`       // List of hosts scanned
+	XMLName  xml.Name   `xml:"nmaprun"`
+	Args     string     `xml:"args,attr"`  // Command line of the scan
+	Start    string     `xml:"start,attr"` // Start time of the scan
+	Hosts    []NmapHost `xml:"host"`       // List of hosts scanned
+	RunStats RunStats   `xml:"runstats"`   // Statistics of the scan
+}
+
+// RunStats represents the statistics of an NMap scan
+type RunStats struct {
+	Hosts HostStats `xml:"hosts"` // Number of hosts by status
+}
+
+// HostStats represents the number of hosts of an NMap scan by status.
+// Hosts that are up without any open ports aren't listed when scanning with --open, but are still counted.
+type HostStats struct {
+	Up    int `xml:"up,attr"`    // Number of hosts that responded
+	Down  int `xml:"down,attr"`  // Number of hosts that didn't respond
+	Total int `xml:"total,attr"` // Number of hosts scanned, zero when the target couldn't be resolved
 }
 
 // NmapHost represents a scanned host
 type NmapHost struct {
-	Addresses []Address `xml:"address"`    // List of addresses for the host
-	Ports     []Port    `xml:"ports>port"` // List of ports for the host
+	StartTime string         `xml:"starttime,attr"`     // Start time of the scan of the host, only set when nmap scanned its ports
+	Status    NmapStatus     `xml:"status"`             // Status of the host
+	Addresses []Address      `xml:"address"`            // List of addresses for the host
+	Hostnames []NmapHostname `xml:"hostnames>hostname"` // List of names of the host
+	Ports     []Port         `xml:"ports>port"`         // List of ports for the host
+}
+
+// NmapHostname represents a name of a scanned host
+type NmapHostname struct {
+	Name string `xml:"name,attr"` // Name
+	Type string `xml:"type,attr"` // Where the name comes from, user or PTR
+}
+
+// NmapStatus represents the status of a scanned host
+type NmapStatus struct {
+	State  string `xml:"state,attr"`  // up or down
+	Reason string `xml:"reason,attr"` // What the state is based on, e.g. syn-ack or no-response
 }
 
+// Address types reported by nmap
+const (
+	AddrTypeIPv4 = "ipv4"
+	AddrTypeIPv6 = "ipv6"
+	AddrTypeMAC  = "mac"
+)
+
 // Address represents an address for a host
 type Address struct {
-	Addr string `xml:"addr,attr"` // Address
+	Addr     string `xml:"addr,attr"`     // Address
+	AddrType string `xml:"addrtype,attr"` // Type of the address (ipv4, ipv6, mac)
+}
+
+// IPAddress returns the first IPv4 or IPv6 address of a scanned host, MAC addresses are skipped
+func (h NmapHost) IPAddress() string {
+	for _, address := range h.Addresses {
+		if address.AddrType == AddrTypeIPv4 || address.AddrType == AddrTypeIPv6 {
+			return address.Addr
+		}
+	}
+	return ""
+}
+
+// MasscanHost represents an entry of a masscan JSON report, masscan reports every open port as an entry of its own
+type MasscanHost struct {
+	IP        string        `json:"ip"`        // Address of the host
+	Timestamp string        `json:"timestamp"` // Time the port was found, in seconds since the epoch
+	Ports     []MasscanPort `json:"ports"`     // Ports of the host, a single one per entry
+}
+
+// MasscanPort represents a port of a masscan JSON report
+type MasscanPort struct {
+	Port   int    `json:"port"`   // Port number
+	Proto  string `json:"proto"`  // Protocol
+	Status string `json:"status"` // State of the port, empty for banners
+	Reason string `json:"reason"` // What the state is based on, e.g. syn-ack
+}
+
+// NaabuResult represents a line of a naabu JSON lines report
+type NaabuResult struct {
+	Host      string    `json:"host"`      // Target the port was found on, a hostname or an IP address
+	IP        string    `json:"ip"`        // Address of the host
+	Port      int       `json:"port"`      // Open port
+	Protocol  string    `json:"protocol"`  // Protocol
+	Timestamp time.Time `json:"timestamp"` // Time the port was found
 }
 
 // Port represents a port for a host
@@ -48,9 +189,26 @@ type State struct {
 }
 
 type Host struct {
-	HostID    string `db:"host_id" json:"host_id,omitempty"`
-	IPAddress string `db:"ip_address" json:"ip_address"`
-	Hostname  string `db:"hostname" json:"hostname"`
+	HostID    string      `db:"host_id" json:"host_id,omitempty"`
+	IPAddress string      `db:"ip_address" json:"ip_address"`
+	Hostname  string      `db:"hostname" json:"hostname"`
+	Owner     string      `db:"owner" json:"owner,omitempty"` // Who is responsible for the host, set by target imports
+	Names     []*HostName `db:"-" json:"names,omitempty"`     // Every name nmap reported for the host during the scan
+}
+
+// HostName represents a name of a host and when it was seen
+type HostName struct {
+	Name      string    `db:"name" json:"name"`
+	Type      string    `db:"type" json:"type"`             // Where the name comes from (user, PTR)
+	FirstSeen time.Time `db:"first_seen" json:"first_seen"` // Time of the first scan that reported the name
+	LastSeen  time.Time `db:"last_seen" json:"last_seen"`   // Time of the newest scan that reported the name
+	Current   bool      `db:"current" json:"current"`       // Whether the newest scan that listed the host reported the name
+}
+
+// NameChange represents a change of the reverse DNS of a host between two scans
+type NameChange struct {
+	Name   string `json:"name"`   // Name of the PTR record
+	Change string `json:"change"` // Change type (ptr_added, ptr_removed)
 }
 
 type ScanResult struct {
@@ -61,9 +219,87 @@ type ScanResult struct {
 	Status    string    `db:"status" json:"status"`
 }
 
+// ScanRun represents a single scan of a host and who launched it
+type ScanRun struct {
+	ScanRunID   string    `db:"scan_run_id" json:"scan_run_id"`
+	IPAddress   string    `db:"ip_address" json:"ip_address"`
+	Hostname    string    `db:"hostname" json:"hostname"`
+	Profile     string    `db:"profile" json:"profile"`
+	InitiatedBy string    `db:"initiated_by" json:"initiated_by"`
+	HostStatus  string    `db:"host_status" json:"host_status"`           // Whether the host was up or down
+	HostReason  string    `db:"host_reason" json:"host_reason,omitempty"` // What nmap based the host status on, e.g. syn-ack
+	Source      string    `db:"source" json:"source"`                     // Whether the scan was run by the service or imported (scan, import)
+	Scanner     string    `db:"scanner" json:"scanner"`                   // Tool that ran the scan (nmap, masscan, naabu)
+	Timestamp   time.Time `db:"timestamp" json:"timestamp"`
+}
+
 type ScanResponse struct {
+	WorkspaceID string         `json:"workspace_id"`
+	ScanRunID   string         `json:"scan_run_id,omitempty"`
 	Host        Host           `json:"host"`
+	HostStatus  string         `json:"host_status"`            // Whether the host was up or down, a host that is up may have no open ports
+	HostReason  string         `json:"host_reason,omitempty"`  // What nmap based the host status on, e.g. syn-ack
+	HostChange  string         `json:"host_change,omitempty"`  // Set when the host went down or came back since its last scan
+	NameChanges []NameChange   `json:"name_changes,omitempty"` // Changes of the reverse DNS of the host since its last scan
+	Historical  bool           `json:"historical,omitempty"`   // Imported scan older than the last scan of the host, recorded without looking for changes
 	ScanResults []*ScanResult  `json:"scan_results"`
 	PortHistory []*ScanResult  `json:"port_history"`
 	Changes     map[int]string `json:"changes,omitempty"`
 }
+
+// HostTagsRequest represents a request to replace the tags of a host
+type HostTagsRequest struct {
+	Tags []string `json:"tags" validate:"unique,dive,required,max=255"` // Tags to assign to the host
+}
+
+// PortChange represents a change of a single port between two scans
+type PortChange struct {
+	Port   int    `json:"port"`   // Port number
+	Change string `json:"change"` // Change type (added, removed)
+}
+
+// PortChanges returns the changes of the scan response as a list sorted by port number
+func (r *ScanResponse) PortChanges() []PortChange {
+	changes := make([]PortChange, 0, len(r.Changes))
+	for port, change := range r.Changes {
+		changes = append(changes, PortChange{Port: port, Change: change})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Port < changes[j].Port
+	})
+
+	return changes
+}
+
+// AvailabilityPeriod represents consecutive scans of a host that found it with the same status
+type AvailabilityPeriod struct {
+	HostStatus string    `json:"host_status"`           // Status of the host during the period
+	HostReason string    `json:"host_reason,omitempty"` // What nmap based the status on during the newest scan of the period
+	From       time.Time `json:"from"`                  // Time of the first scan of the period
+	To         time.Time `json:"to"`                    // Time of the newest scan of the period
+	Scans      int       `json:"scans"`                 // Number of scans during the period
+}
+
+// AvailabilityHistory collapses the scan runs of a host, newest first, into periods of the same host status, newest first
+func AvailabilityHistory(runs []*ScanRun) []*AvailabilityPeriod {
+	periods := []*AvailabilityPeriod{}
+	for _, run := range runs {
+		if len(periods) > 0 && periods[len(periods)-1].HostStatus == run.HostStatus {
+			period := periods[len(periods)-1]
+			period.From = run.Timestamp
+			period.Scans++
+			continue
+		}
+
+		periods = append(periods, &AvailabilityPeriod{
+			HostStatus: run.HostStatus,
+			HostReason: run.HostReason,
+			From:       run.Timestamp,
+			To:         run.Timestamp,
+			Scans:      1,
+		})
+	}
+
+	return periods
+}
@@ -0,0 +1,28 @@
+package scan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvailabilityHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 8, d, 0, 0, 0, 0, time.UTC) }
+
+	runs := []*ScanRun{
+		{HostStatus: HostStatusUp, HostReason: "syn-ack", Timestamp: day(5)},
+		{HostStatus: HostStatusDown, Timestamp: day(4)},
+		{HostStatus: HostStatusDown, Timestamp: day(3)},
+		{HostStatus: HostStatusUp, HostReason: "echo-reply", Timestamp: day(2)},
+		{HostStatus: HostStatusUp, HostReason: "syn-ack", Timestamp: day(1)},
+	}
+
+	assert.Equal(t, []*AvailabilityPeriod{
+		{HostStatus: HostStatusUp, HostReason: "syn-ack", From: day(5), To: day(5), Scans: 1},
+		{HostStatus: HostStatusDown, From: day(3), To: day(4), Scans: 2},
+		{HostStatus: HostStatusUp, HostReason: "echo-reply", From: day(1), To: day(2), Scans: 2},
+	}, AvailabilityHistory(runs))
+
+	assert.Empty(t, AvailabilityHistory(nil))
+}
@@ -0,0 +1,162 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var (
+	portsPattern    = regexp.MustCompile(`^[0-9TU:,-]+$`)
+	numberPattern   = regexp.MustCompile(`^[0-9]+$`)
+	durationPattern = regexp.MustCompile(`^[0-9]+(ms|s|m|h)?$`)
+	hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9_.])?$`)
+)
+
+// allowedFlags is the allowlist of nmap options profiles and requests may set.
+// The value is the pattern the option's argument must match, or nil for options without an argument.
+var allowedFlags = map[string]*regexp.Regexp{
+	"-p":             portsPattern,
+	"-p-":            nil,
+	"-F":             nil,
+	"--top-ports":    numberPattern,
+	"--open":         nil,
+	"-sT":            nil,
+	"-sS":            nil,
+	"-sV":            nil,
+	"-Pn":            nil,
+	"-n":             nil,
+	"-6":             nil,
+	"-T0":            nil,
+	"-T1":            nil,
+	"-T2":            nil,
+	"-T3":            nil,
+	"-T4":            nil,
+	"-T5":            nil,
+	"--max-retries":  numberPattern,
+	"--host-timeout": durationPattern,
+}
+
+// outputFlags are always set by the builder so the output can be parsed
+var outputFlags = []string{"--stats-every", "0", "-oX", "-"}
+
+// NmapCommand builds the arguments of an nmap command.
+// Only allowlisted options are accepted and targets are placed after "--" so they can never be read as options.
+type NmapCommand struct {
+	flags   []string
+	targets []string
+}
+
+// NewNmapCommand creates a new NmapCommand
+func NewNmapCommand() *NmapCommand {
+	return &NmapCommand{}
+}
+
+// AddFlag adds an allowlisted option, along with its argument when the option takes one
+func (c *NmapCommand) AddFlag(flag string, value ...string) error {
+	pattern, ok := allowedFlags[flag]
+	if !ok {
+		return fmt.Errorf("nmap option %q is not allowed", flag)
+	}
+
+	if pattern == nil {
+		if len(value) != 0 {
+			return fmt.Errorf("nmap option %q doesn't take an argument", flag)
+		}
+		c.flags = append(c.flags, flag)
+		return nil
+	}
+
+	if len(value) != 1 {
+		return fmt.Errorf("nmap option %q requires a single argument", flag)
+	}
+	if !pattern.MatchString(value[0]) || strings.HasPrefix(value[0], "-") {
+		return fmt.Errorf("invalid argument %q for nmap option %q", value[0], flag)
+	}
+
+	c.flags = append(c.flags, flag, value[0])
+	return nil
+}
+
+// AddArgs adds a list of options as written on the command line, e.g. the options of a profile
+func (c *NmapCommand) AddArgs(args []string) error {
+	for i := 0; i < len(args); i++ {
+		pattern, ok := allowedFlags[args[i]]
+		if !ok {
+			return fmt.Errorf("nmap option %q is not allowed", args[i])
+		}
+
+		if pattern == nil {
+			if err := c.AddFlag(args[i]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if i+1 >= len(args) {
+			return fmt.Errorf("nmap option %q requires a single argument", args[i])
+		}
+		if err := c.AddFlag(args[i], args[i+1]); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+// AddTarget adds an IP address or hostname to scan
+func (c *NmapCommand) AddTarget(target string) error {
+	if target == "" {
+		return fmt.Errorf("empty nmap target")
+	}
+	if strings.HasPrefix(target, "-") {
+		return fmt.Errorf("nmap target %q can't start with '-'", target)
+	}
+	if net.ParseIP(target) == nil && !hostnamePattern.MatchString(target) {
+		return fmt.Errorf("nmap target %q is not an IP address or hostname", target)
+	}
+
+	c.targets = append(c.targets, target)
+	return nil
+}
+
+// Args returns the arguments to run nmap with
+func (c *NmapCommand) Args() ([]string, error) {
+	if len(c.targets) == 0 {
+		return nil, fmt.Errorf("nmap command has no targets")
+	}
+
+	args := append([]string{}, c.flags...)
+	args = append(args, outputFlags...)
+	args = append(args, "--")
+	return append(args, c.targets...), nil
+}
+
+// NmapVersion returns the version of the nmap binary on the PATH
+func NmapVersion(ctx context.Context) (string, error) {
+	path, err := exec.LookPath("nmap")
+	if err != nil {
+		return "", err
+	}
+
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+
+	return parseNmapVersion(string(output))
+}
+
+// parseNmapVersion reads the version from the output of nmap --version, e.g. "Nmap version 7.94 ( https://nmap.org )"
+func parseNmapVersion(output string) (string, error) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "Nmap" && fields[1] == "version" {
+			return fields[2], nil
+		}
+	}
+	return "", fmt.Errorf("unexpected nmap --version output %q", output)
+}
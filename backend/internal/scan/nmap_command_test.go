@@ -0,0 +1,91 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNmapCommand_AddTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{name: "Test Case 1: IPv4 Address", target: "34.117.168.233"},
+		{name: "Test Case 2: IPv6 Address", target: "2001:db8::1"},
+		{name: "Test Case 3: Hostname", target: "www.parkdna.com"},
+		{name: "Test Case 4: Input List Injection", target: "-iL/etc/passwd", wantErr: true},
+		{name: "Test Case 5: Script Injection", target: "--script=http-shellshock", wantErr: true},
+		{name: "Test Case 6: Output File Injection", target: "-oN/tmp/pwned", wantErr: true},
+		{name: "Test Case 7: End Of Options Marker", target: "--", wantErr: true},
+		{name: "Test Case 8: Embedded Space", target: "www.example.com -iL /etc/passwd", wantErr: true},
+		{name: "Test Case 9: Shell Metacharacters", target: "www.example.com;id", wantErr: true},
+		{name: "Test Case 10: Empty Target", target: "", wantErr: true},
+		{name: "Test Case 11: Newline", target: "www.example.com\n-iL", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewNmapCommand().AddTarget(tt.target)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNmapCommand_AddArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "Test Case 1: Default Profile", args: Profiles[DefaultProfile]},
+		{name: "Test Case 2: Quick Profile", args: Profiles["quick"]},
+		{name: "Test Case 3: Full Profile", args: Profiles["full"]},
+		{name: "Test Case 4: Script Option", args: []string{"--script", "http-shellshock"}, wantErr: true},
+		{name: "Test Case 5: Input List Option", args: []string{"-iL", "/etc/passwd"}, wantErr: true},
+		{name: "Test Case 6: Inline Option Value", args: []string{"--script=vuln"}, wantErr: true},
+		{name: "Test Case 7: Option As Port Argument", args: []string{"-p", "-iL"}, wantErr: true},
+		{name: "Test Case 8: Invalid Port Argument", args: []string{"-p", "80;id"}, wantErr: true},
+		{name: "Test Case 9: Missing Argument", args: []string{"--open", "-p"}, wantErr: true},
+		{name: "Test Case 10: Output Option", args: []string{"-oN", "/tmp/pwned"}, wantErr: true},
+		{name: "Test Case 11: IPv6 Option", args: []string{"-6", "-F"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewNmapCommand().AddArgs(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNmapCommand_Args(t *testing.T) {
+	cmd := NewNmapCommand()
+	require.NoError(t, cmd.AddArgs([]string{"-p", "0-1000", "--open", "-T5"}))
+	require.NoError(t, cmd.AddTarget("www.parkdna.com"))
+
+	args, err := cmd.Args()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-p", "0-1000", "--open", "-T5", "--stats-every", "0", "-oX", "-", "--", "www.parkdna.com"}, args)
+
+	_, err = NewNmapCommand().Args()
+	assert.Error(t, err, "a command without targets should be rejected")
+}
+
+func TestParseNmapVersion(t *testing.T) {
+	output := "Nmap version 7.94 ( https://nmap.org )\nPlatform: x86_64-pc-linux-gnu\nCompiled with: liblua-5.4.4 openssl-3.0.9\n"
+	version, err := parseNmapVersion(output)
+	require.NoError(t, err)
+	assert.Equal(t, "7.94", version)
+
+	_, err = parseNmapVersion("command not found")
+	assert.Error(t, err)
+}
@@ -0,0 +1,302 @@
+package scan
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/logging"
+	"backend/internal/tracing"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// masscanTrailingComma matches the comma older masscan versions leave after the last entry of a JSON report
+var masscanTrailingComma = regexp.MustCompile(`,\s*\]\s*$`)
+
+// reportedPort represents an open port found by a scanner that reports ports rather than hosts
+type reportedPort struct {
+	IPAddress string
+	Hostname  string
+	Port      int
+	Reason    string
+	Timestamp time.Time
+}
+
+// ImportReport records the hosts of a scan report produced outside of the service, one scan run per host,
+// and compares each of them against its last scan like the scans run by the service.
+// The request sets the workspace, the caller and the profile the report was produced with, ImportProfile when unknown.
+func (s *ScanClient) ImportReport(ctx context.Context, request ScanRequestMapped, format string, report []byte) (_ []*ScanResponse, err error) {
+	ctx, span := tracer.Start(ctx, "ScanClient.ImportReport", trace.WithAttributes(
+		attribute.String("scan.report_format", format),
+		attribute.String("scan.profile", request.Profile),
+		attribute.String("scan.workspace_id", request.WorkspaceID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	profile := request.Profile
+	if profile == "" {
+		profile = ImportProfile
+	}
+
+	scans, err := parseReport(format, report, profile)
+	if err != nil {
+		logging.FromContext(ctx, s.Logger).Warn("invalid scan report", zap.String("format", format), zap.Error(err))
+		return nil, apierror.Invalid("file", format, fmt.Sprintf("Must be a %s report: %s", format, err))
+	}
+	if len(scans) == 0 {
+		return nil, apierror.Invalid("file", format, "The report doesn't list any host")
+	}
+
+	span.SetAttributes(attribute.Int("scan.addresses", len(scans)))
+
+	var responses []*ScanResponse
+	for _, scanned := range scans {
+		response, err := s.recordScan(ctx, request, scanned)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// parseReport parses a scan report in a format into one scan per host
+func parseReport(format string, report []byte, profile string) ([]*hostScan, error) {
+	switch format {
+	case ReportNmapXML:
+		return parseNmapReport(report, profile)
+	case ReportMasscanJSON:
+		ports, err := parseMasscanJSON(report)
+		return groupReportedPorts(ports, profile, ScannerMasscan), err
+	case ReportMasscanList:
+		ports, err := parseMasscanList(report)
+		return groupReportedPorts(ports, profile, ScannerMasscan), err
+	case ReportNaabuJSONL:
+		ports, err := parseNaabuJSONL(report)
+		return groupReportedPorts(ports, profile, ScannerNaabu), err
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// parseNmapReport parses an nmap XML report listing any number of hosts, as produced by nmap -oX outside of the service.
+// Only open ports are read, as if the report was produced with --open like the scans of the profiles.
+func parseNmapReport(output []byte, profile string) ([]*hostScan, error) {
+	var nmapRun NmapRun
+	if err := xml.Unmarshal(output, &nmapRun); err != nil {
+		return nil, err
+	}
+
+	nmapStartTime, err := strconv.ParseInt(nmapRun.Start, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time %q", nmapRun.Start)
+	}
+
+	var scans []*hostScan
+	for _, h := range nmapRun.Hosts {
+		host := Host{IPAddress: h.IPAddress()}
+		if host.IPAddress == "" {
+			continue
+		}
+
+		scanTime := time.Unix(nmapStartTime, 0)
+		if hostStartTime, err := strconv.ParseInt(h.StartTime, 10, 64); err == nil && hostStartTime > 0 {
+			scanTime = time.Unix(hostStartTime, 0)
+		}
+
+		run := &ScanRun{Profile: profile, Source: SourceImport, Scanner: ScannerNmap, HostStatus: HostStatusUp, HostReason: h.Status.Reason, Timestamp: scanTime}
+		if h.Status.State == HostStatusDown {
+			run.HostStatus = HostStatusDown
+		}
+
+		for _, hostname := range h.Hostnames {
+			if hostname.Type == HostNameTypeUser && host.Hostname == "" {
+				host.Hostname = hostname.Name
+			}
+			host.Names = append(host.Names, &HostName{Name: hostname.Name, Type: hostname.Type, FirstSeen: scanTime, LastSeen: scanTime, Current: true})
+		}
+
+		var scanResults []*ScanResult
+		for _, port := range h.Ports {
+			if port.State.State != "open" {
+				continue
+			}
+			scanResults = append(scanResults, &ScanResult{
+				IPAddress: host.IPAddress,
+				Timestamp: scanTime,
+				Port:      port.PortID,
+				Status:    port.State.State,
+			})
+		}
+
+		// The reverse DNS of a host is only looked up when it is up
+		scans = append(scans, &hostScan{Host: host, Run: run, Results: scanResults, Listed: run.HostStatus == HostStatusUp})
+	}
+
+	return scans, nil
+}
+
+// parseMasscanJSON parses a masscan JSON report, produced with -oJ. Banners are skipped.
+func parseMasscanJSON(report []byte) ([]*reportedPort, error) {
+	report = masscanTrailingComma.ReplaceAll(bytes.TrimSpace(report), []byte("]"))
+
+	var hosts []MasscanHost
+	if err := json.Unmarshal(report, &hosts); err != nil {
+		return nil, err
+	}
+
+	var ports []*reportedPort
+	for i, host := range hosts {
+		if net.ParseIP(host.IP) == nil {
+			return nil, fmt.Errorf("entry %d: invalid IP address %q", i+1, host.IP)
+		}
+		timestamp, err := strconv.ParseInt(host.Timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: invalid timestamp %q", i+1, host.Timestamp)
+		}
+
+		for _, port := range host.Ports {
+			if port.Status != "open" {
+				continue
+			}
+			ports = append(ports, &reportedPort{IPAddress: host.IP, Port: port.Port, Reason: port.Reason, Timestamp: time.Unix(timestamp, 0)})
+		}
+	}
+
+	return ports, nil
+}
+
+// parseMasscanList parses a masscan list report, produced with -oL, made of lines like "open tcp 80 10.0.0.1 1691862400".
+// Comments and banners are skipped.
+func parseMasscanList(report []byte) ([]*reportedPort, error) {
+	var ports []*reportedPort
+	scanner := bufio.NewScanner(bytes.NewReader(report))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("line %d: expected status, protocol, port, address and timestamp", lineNumber)
+		}
+		if fields[0] != "open" {
+			continue
+		}
+
+		port, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid port %q", lineNumber, fields[2])
+		}
+		if net.ParseIP(fields[3]) == nil {
+			return nil, fmt.Errorf("line %d: invalid IP address %q", lineNumber, fields[3])
+		}
+		timestamp, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp %q", lineNumber, fields[4])
+		}
+
+		ports = append(ports, &reportedPort{IPAddress: fields[3], Port: port, Timestamp: time.Unix(timestamp, 0)})
+	}
+
+	return ports, scanner.Err()
+}
+
+// parseNaabuJSONL parses a naabu report, produced with -json, made of one JSON object per open port
+func parseNaabuJSONL(report []byte) ([]*reportedPort, error) {
+	var ports []*reportedPort
+	scanner := bufio.NewScanner(bytes.NewReader(report))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var result NaabuResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		port := &reportedPort{IPAddress: result.IP, Port: result.Port, Timestamp: result.Timestamp.Truncate(time.Second)}
+		if net.ParseIP(result.Host) != nil {
+			port.IPAddress = result.Host
+		} else {
+			port.Hostname = result.Host
+		}
+		if result.IP != "" {
+			port.IPAddress = result.IP
+		}
+		if net.ParseIP(port.IPAddress) == nil {
+			return nil, fmt.Errorf("line %d: invalid IP address %q", lineNumber, port.IPAddress)
+		}
+		if result.Timestamp.IsZero() {
+			return nil, fmt.Errorf("line %d: missing timestamp", lineNumber)
+		}
+
+		ports = append(ports, port)
+	}
+
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return nil, errors.New("line too long")
+	}
+	return ports, scanner.Err()
+}
+
+// groupReportedPorts turns the open ports found by a scanner into one scan per host, in the order the hosts were first reported.
+// Every reported host is up and its scan starts with the first port found. The scanner doesn't look up names,
+// so those recorded for the host are left untouched.
+func groupReportedPorts(ports []*reportedPort, profile string, scanner string) []*hostScan {
+	var scans []*hostScan
+	byAddress := make(map[string]*hostScan)
+	seen := make(map[string]bool)
+	for _, port := range ports {
+		scanned, ok := byAddress[port.IPAddress]
+		if !ok {
+			scanned = &hostScan{
+				Host: Host{IPAddress: port.IPAddress},
+				Run:  &ScanRun{Profile: profile, Source: SourceImport, Scanner: scanner, HostStatus: HostStatusUp, HostReason: port.Reason, Timestamp: port.Timestamp},
+			}
+			byAddress[port.IPAddress] = scanned
+			scans = append(scans, scanned)
+		}
+
+		if scanned.Host.Hostname == "" {
+			scanned.Host.Hostname = port.Hostname
+		}
+		if port.Timestamp.Before(scanned.Run.Timestamp) {
+			scanned.Run.Timestamp = port.Timestamp
+		}
+
+		// Ports found more than once, e.g. by several probes, are recorded once
+		key := port.IPAddress + "|" + strconv.Itoa(port.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		scanned.Results = append(scanned.Results, &ScanResult{IPAddress: port.IPAddress, Port: port.Port, Status: "open"})
+	}
+
+	// Results are recorded at the time of the scan of their host
+	for _, scanned := range scans {
+		for _, result := range scanned.Results {
+			result.Timestamp = scanned.Run.Timestamp
+		}
+	}
+
+	return scans
+}
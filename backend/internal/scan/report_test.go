@@ -0,0 +1,106 @@
+package scan
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseNmapReport(t *testing.T) {
+	// Report of a scan run by hand, without --open
+	sample, err := os.ReadFile("../../scripts/0-1000")
+	require.NoError(t, err)
+
+	scans, err := parseNmapReport(sample, ImportProfile)
+	require.NoError(t, err)
+	require.Len(t, scans, 1)
+	assert.Equal(t, "34.117.168.233", scans[0].Host.IPAddress)
+	assert.Equal(t, "www.parkdna.com", scans[0].Host.Hostname)
+	assert.Len(t, scans[0].Host.Names, 2)
+	assert.Equal(t, &ScanRun{Profile: ImportProfile, Source: SourceImport, Scanner: ScannerNmap, HostStatus: HostStatusUp, HostReason: "syn-ack", Timestamp: time.Unix(1691862400, 0)}, scans[0].Run)
+	assert.Len(t, scans[0].Results, 2)
+	assert.True(t, scans[0].Listed)
+
+	const hosts = `<nmaprun start="1691862400">
+<host starttime="1691862410"><status state="up" reason="arp-response"/><address addr="192.168.1.10" addrtype="ipv4"/><address addr="00:11:22:33:44:55" addrtype="mac"/>
+<ports><port protocol="tcp" portid="22"><state state="open"/></port><port protocol="tcp" portid="23"><state state="closed"/></port></ports>
+</host>
+<host><status state="down" reason="no-response"/><address addr="192.168.1.11" addrtype="ipv4"/></host>
+<runstats><hosts up="1" down="1" total="2"/></runstats>
+</nmaprun>`
+	scans, err = parseNmapReport([]byte(hosts), DefaultProfile)
+	require.NoError(t, err)
+	require.Len(t, scans, 2)
+	assert.Equal(t, time.Unix(1691862410, 0), scans[0].Run.Timestamp)
+	assert.Equal(t, []*ScanResult{{IPAddress: "192.168.1.10", Timestamp: time.Unix(1691862410, 0), Port: 22, Status: "open"}}, scans[0].Results)
+	assert.Equal(t, "192.168.1.11", scans[1].Host.IPAddress)
+	assert.Equal(t, HostStatusDown, scans[1].Run.HostStatus)
+	assert.Equal(t, time.Unix(1691862400, 0), scans[1].Run.Timestamp)
+	assert.False(t, scans[1].Listed)
+
+	_, err = parseNmapReport([]byte(`<masscan start="1691862400"></masscan>`), ImportProfile)
+	assert.Error(t, err, "a document that isn't an nmap report should be rejected")
+}
+
+func Test_parseReport_Masscan(t *testing.T) {
+	const jsonReport = `[
+{   "ip": "10.0.0.1",   "timestamp": "1691862405", "ports": [ {"port": 443, "proto": "tcp", "status": "open", "reason": "syn-ack", "ttl": 64} ] },
+{   "ip": "10.0.0.2",   "timestamp": "1691862401", "ports": [ {"port": 22, "proto": "tcp", "status": "open", "reason": "syn-ack", "ttl": 64} ] },
+{   "ip": "10.0.0.1",   "timestamp": "1691862400", "ports": [ {"port": 80, "proto": "tcp", "status": "open", "reason": "syn-ack", "ttl": 64} ] },
+{   "ip": "10.0.0.1",   "timestamp": "1691862406", "ports": [ {"port": 80, "proto": "tcp", "service": {"name": "http", "banner": "nginx"} } ] },
+]`
+	const listReport = `#masscan
+open tcp 443 10.0.0.1 1691862405
+open tcp 22 10.0.0.2 1691862401
+open tcp 80 10.0.0.1 1691862400
+banner tcp 80 10.0.0.1 1691862406 http nginx
+# end
+`
+
+	for format, report := range map[string]string{ReportMasscanJSON: jsonReport, ReportMasscanList: listReport} {
+		scans, err := parseReport(format, []byte(report), ImportProfile)
+		require.NoError(t, err, format)
+		require.Len(t, scans, 2, format)
+
+		assert.Equal(t, "10.0.0.1", scans[0].Host.IPAddress, format)
+		assert.Equal(t, ScannerMasscan, scans[0].Run.Scanner, format)
+		assert.Equal(t, SourceImport, scans[0].Run.Source, format)
+		assert.Equal(t, HostStatusUp, scans[0].Run.HostStatus, format)
+		assert.Equal(t, time.Unix(1691862400, 0), scans[0].Run.Timestamp, format)
+		assert.Equal(t, []*ScanResult{
+			{IPAddress: "10.0.0.1", Timestamp: time.Unix(1691862400, 0), Port: 443, Status: "open"},
+			{IPAddress: "10.0.0.1", Timestamp: time.Unix(1691862400, 0), Port: 80, Status: "open"},
+		}, scans[0].Results, format)
+		assert.False(t, scans[0].Listed, "%s doesn't look up names", format)
+
+		assert.Equal(t, "10.0.0.2", scans[1].Host.IPAddress, format)
+		assert.Len(t, scans[1].Results, 1, format)
+	}
+
+	_, err := parseReport(ReportMasscanList, []byte("open tcp http 10.0.0.1 1691862400\n"), ImportProfile)
+	assert.Error(t, err)
+}
+
+func Test_parseReport_Naabu(t *testing.T) {
+	const report = `{"host":"www.example.com","ip":"93.184.216.34","port":443,"protocol":"tcp","tls":false,"timestamp":"2023-08-12T17:46:41.123456789Z"}
+{"host":"www.example.com","ip":"93.184.216.34","port":80,"protocol":"tcp","tls":false,"timestamp":"2023-08-12T17:46:40.5Z"}
+
+{"ip":"203.0.113.10","port":22,"timestamp":"2023-08-12T17:46:42Z"}
+`
+
+	scans, err := parseReport(ReportNaabuJSONL, []byte(report), DefaultProfile)
+	require.NoError(t, err)
+	require.Len(t, scans, 2)
+
+	assert.Equal(t, Host{IPAddress: "93.184.216.34", Hostname: "www.example.com"}, scans[0].Host)
+	assert.Equal(t, &ScanRun{Profile: DefaultProfile, Source: SourceImport, Scanner: ScannerNaabu, HostStatus: HostStatusUp, Timestamp: time.Date(2023, 8, 12, 17, 46, 40, 0, time.UTC)}, scans[0].Run)
+	assert.Len(t, scans[0].Results, 2)
+
+	assert.Equal(t, Host{IPAddress: "203.0.113.10"}, scans[1].Host)
+
+	_, err = parseReport(ReportNaabuJSONL, []byte(`{"host":"www.example.com","port":443,"timestamp":"2023-08-12T17:46:41Z"}`), ImportProfile)
+	assert.Error(t, err, "a result without an address should be rejected")
+}
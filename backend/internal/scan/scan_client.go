@@ -1,19 +1,36 @@
 package scan
 
 import (
+	"backend/internal/apierror"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/ratelimit"
+	"backend/internal/tracing"
+	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"net"
 	"os/exec"
+	"sort"
 	"strconv"
 	"time"
 )
 
+// tracer creates the spans of the scan package
+var tracer = otel.Tracer("backend/internal/scan")
+
 // ScanClient represents a client for scanning ports
 type ScanClient struct {
-	Logger   *zap.Logger // Logger
-	DBClient *DBClient   // Database client
+	Logger   *zap.Logger      // Logger
+	DBClient *DBClient        // Database client
+	Queue    *ratelimit.Queue // Caps the number of nmap processes running at once, unlimited when nil
+	Timeout  time.Duration    // Maximum duration of an nmap run, unlimited when zero
 }
 
 // NewScanClient creates a new ScanClient
@@ -24,64 +41,253 @@ func NewScanClient(logger *zap.Logger, DBClient *DBClient) *ScanClient {
 	}
 }
 
-func (s *ScanClient) ScanForOpenPorts(ctx context.Context, request ScanRequestMapped) (*ScanResponse, error) {
-	var host Host
+// ScanForOpenPorts scans the requested host and records the results. A hostname is resolved to all of its A and AAAA records
+// and every address is scanned and recorded under the hostname, one response per address sorted with IPv4 addresses first.
+func (s *ScanClient) ScanForOpenPorts(ctx context.Context, request ScanRequestMapped) (_ []*ScanResponse, err error) {
+	ctx, span := tracer.Start(ctx, "ScanClient.ScanForOpenPorts", trace.WithAttributes(
+		attribute.StringSlice("scan.ips", request.IPs),
+		attribute.StringSlice("scan.hostnames", request.Hostnames),
+		attribute.String("scan.profile", request.Profile),
+		attribute.String("scan.workspace_id", request.WorkspaceID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	hosts, err := s.requestHosts(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("scan.addresses", len(hosts)))
+
+	var responses []*ScanResponse
+	for _, host := range hosts {
+		response, err := s.scanHost(ctx, request, host)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// requestHosts returns the addresses to scan for a request. A hostname is scanned at the addresses it was checked
+// against the scope at, and only resolved here when the request doesn't carry them.
+func (s *ScanClient) requestHosts(ctx context.Context, request ScanRequestMapped) ([]Host, error) {
 	if len(request.IPs) > 0 {
-		host.IPAddress = request.IPs[0]
+		return []Host{{IPAddress: request.IPs[0]}}, nil
+	}
+
+	hostname := request.Hostnames[0]
+	var addresses []string
+	if checked, ok := request.Addresses[hostname]; ok {
+		addrs := make([]net.IPAddr, 0, len(checked))
+		for _, address := range checked {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(address)})
+		}
+		addresses = sortAddresses(addrs)
 	} else {
-		host.Hostname = request.Hostnames[0]
+		var err error
+		addresses, err = ResolveHost(ctx, hostname)
+		if err != nil {
+			logging.FromContext(ctx, s.Logger).Warn("unable to resolve host", zap.String("hostname", hostname), zap.Error(err))
+			return nil, apierror.Invalid("ips_or_hostnames", "resolvable", fmt.Sprintf("Host %s doesn't resolve to any address", hostname))
+		}
+	}
+
+	hosts := make([]Host, 0, len(addresses))
+	for _, address := range addresses {
+		hosts = append(hosts, Host{IPAddress: address, Hostname: hostname})
+	}
+	return hosts, nil
+}
+
+// scanHost scans a single address of the requested host, compares the results against its last scan and records them
+func (s *ScanClient) scanHost(ctx context.Context, request ScanRequestMapped, host Host) (*ScanResponse, error) {
+	logger := logging.FromContext(ctx, s.Logger)
+
+	target := host.IPAddress
+	if host.Hostname != "" {
+		target = fmt.Sprintf("%s (%s)", host.Hostname, host.IPAddress)
+	}
+
+	// Wait for a free nmap slot
+	if s.Queue != nil {
+		if err := s.Queue.Acquire(ctx); err != nil {
+			if errors.Is(err, ratelimit.ErrQueueFull) {
+				return nil, apierror.Wrap(apierror.CodeQueueFull, err)
+			}
+			return nil, err
+		}
 	}
 
 	// Scan the host using NMap cli
-	scannedHost, scannedPorts, err := s.execScanCommand(ctx, host)
-	if err != nil {
-		s.Logger.Error("error running nmap command", zap.Any("host", host))
-		return nil, fmt.Errorf("error running nmap command for host %s", host.IPAddress)
+	scanCtx, cancel := ctx, context.CancelFunc(func() {})
+	if s.Timeout > 0 {
+		scanCtx, cancel = context.WithTimeout(ctx, s.Timeout)
+	}
+	scanned, err := s.execScanCommand(scanCtx, host, request.Profile)
+	timedOut := errors.Is(scanCtx.Err(), context.DeadlineExceeded)
+	cancel()
+	if s.Queue != nil {
+		s.Queue.Release()
+	}
+	var apiErr *apierror.Error
+	switch {
+	case errors.As(err, &apiErr):
+		return nil, err
+	case timedOut:
+		logger.Error("nmap command timed out", zap.Any("host", host), zap.Duration("timeout", s.Timeout))
+		return nil, &apierror.Error{Code: apierror.CodeTimeout, Message: fmt.Sprintf("Scan of host %s timed out after %s", target, s.Timeout), Err: err}
+	case err != nil:
+		logger.Error("error running nmap command", zap.Any("host", host), zap.Error(err))
+		return nil, &apierror.Error{Code: apierror.CodeScanFailed, Message: fmt.Sprintf("Unable to scan host %s", target), Err: err}
 	}
 
-	if len(scannedPorts) == 0 {
-		s.Logger.Error("no ports found", zap.Any("host", host))
-		return nil, fmt.Errorf("no ports found for host %s", host.IPAddress)
+	return s.recordScan(ctx, request, scanned)
+}
+
+// recordScan compares what a scan found out about a host against its last scan and records it
+func (s *ScanClient) recordScan(ctx context.Context, request ScanRequestMapped, scanned *hostScan) (*ScanResponse, error) {
+	logger := logging.FromContext(ctx, s.Logger)
+
+	scannedHost, run, scannedPorts := scanned.Host, scanned.Run, scanned.Results
+
+	target := scannedHost.IPAddress
+	if scannedHost.Hostname != "" {
+		target = fmt.Sprintf("%s (%s)", scannedHost.Hostname, scannedHost.IPAddress)
 	}
 
-	s.Logger.Debug("Scanned Host", zap.Any("scannedHost", scannedHost), zap.Any("scannedPorts", scannedPorts))
+	logger.Debug("Scanned Host", zap.Any("scannedHost", scannedHost), zap.String("hostStatus", run.HostStatus), zap.Any("scannedPorts", scannedPorts))
 
-	// Get the port history from the database
-	portHistory, err := s.DBClient.QueryPortHistory(ctx, scannedHost.IPAddress, scannedPorts)
+	// An imported report older than the last scan of the host is history, comparing the current state against it would
+	// report changes that the next scan reverses
+	if run.Source == SourceImport {
+		lastScanTime, err := s.DBClient.QueryLastScanTime(ctx, request.WorkspaceID, scannedHost.IPAddress)
+		if err != nil {
+			logger.Error("error querying last scan time", zap.Error(err))
+			return nil, apierror.Internal(fmt.Sprintf("Unable to query scan history of host %s", target), err)
+		}
+		if run.Timestamp.Before(lastScanTime) {
+			return s.recordHistory(ctx, request, scanned)
+		}
+	}
+
+	// Get the ports found open by the last scan of the host with the same profile, other profiles scan other ports
+	lastPorts, err := s.DBClient.QueryLastScanResults(ctx, request.WorkspaceID, scannedHost.IPAddress, run.Profile)
 	if err != nil {
-		s.Logger.Error("error querying port history", zap.Error(err))
-		return nil, fmt.Errorf("error querying port history for host %s", host.IPAddress)
+		logger.Error("error querying last scan results", zap.Error(err))
+		return nil, apierror.Internal(fmt.Sprintf("Unable to query port history of host %s", target), err)
 	}
 
-	s.Logger.Debug("Port History", zap.Any("portHistory", portHistory))
+	// Get the port history from the database, including the ports that were open before
+	portHistory, err := s.DBClient.QueryPortHistory(ctx, request.WorkspaceID, scannedHost.IPAddress, uniquePorts(scannedPorts, lastPorts))
+	if err != nil {
+		logger.Error("error querying port history", zap.Error(err))
+		return nil, apierror.Internal(fmt.Sprintf("Unable to query port history of host %s", target), err)
+	}
 
-	// Check the newest scanned host's ports against the port last scan
-	changedPorts := comparePorts(scannedPorts, portHistory)
+	logger.Debug("Port History", zap.Any("portHistory", portHistory))
+
+	// Check the newest scanned host's ports against the last scan, every port open before is removed when nothing is open anymore.
+	// The ports of a host that is down are unknown, so they are left as they were.
+	changedPorts := map[int]string{}
+	if run.HostStatus == HostStatusUp {
+		changedPorts = comparePorts(scannedPorts, lastPorts)
+	}
 
-	s.Logger.Debug("Changed Ports", zap.Any("changedPorts", changedPorts))
+	logger.Debug("Changed Ports", zap.Any("changedPorts", changedPorts))
 
-	// Update the database with the new and updated ports
-	err = s.DBClient.UpsertScanResults(ctx, scannedHost, scannedPorts)
+	// Check whether the host went down or came back since its last scan, with any profile
+	lastHostStatus, err := s.DBClient.QueryLastHostStatus(ctx, request.WorkspaceID, scannedHost.IPAddress)
 	if err != nil {
-		s.Logger.Error("error updating database", zap.Error(err))
-		return nil, fmt.Errorf("error updating database for host %s", host.IPAddress)
+		logger.Error("error querying last host status", zap.Error(err))
+		return nil, apierror.Internal(fmt.Sprintf("Unable to query status history of host %s", target), err)
 	}
+	hostChange := compareHostStatus(run.HostStatus, lastHostStatus)
 
-	s.Logger.Debug("Updated Database with new and updated ports")
+	// Check the reverse DNS of the host against its last scan, its names are only known when nmap listed it
+	var nameChanges []NameChange
+	if scanned.Listed {
+		lastNames, err := s.DBClient.QueryHostNames(ctx, request.WorkspaceID, scannedHost.IPAddress)
+		if err != nil {
+			logger.Error("error querying host names", zap.Error(err))
+			return nil, apierror.Internal(fmt.Sprintf("Unable to query names of host %s", target), err)
+		}
+		nameChanges = comparePTRs(scannedHost.Names, lastNames)
+	}
+
+	// Update the database with the new and updated ports, a run without open ports is recorded as well
+	run.InitiatedBy = request.InitiatedBy
+
+	err = s.DBClient.UpsertScanResults(ctx, request.WorkspaceID, scannedHost, run, scannedPorts)
+	if err != nil {
+		logger.Error("error updating database", zap.Error(err))
+		return nil, apierror.Internal(fmt.Sprintf("Unable to save scan results of host %s", target), err)
+	}
+
+	if scanned.Listed {
+		if err := s.DBClient.UpsertHostNames(ctx, request.WorkspaceID, scannedHost.IPAddress, scannedHost.Names); err != nil {
+			logger.Error("error updating host names", zap.Error(err))
+			return nil, apierror.Internal(fmt.Sprintf("Unable to save names of host %s", target), err)
+		}
+	}
+
+	logger.Debug("Updated Database with new and updated ports")
+
+	metrics.PortsDiscovered.Add(float64(len(scannedPorts)))
+	for _, change := range changedPorts {
+		metrics.ChangesTotal.WithLabelValues(change).Inc()
+	}
+	if hostChange != "" {
+		metrics.ChangesTotal.WithLabelValues(hostChange).Inc()
+	}
+	for _, change := range nameChanges {
+		metrics.ChangesTotal.WithLabelValues(change.Change).Inc()
+	}
 
 	// Return the ports & changes
 	response := &ScanResponse{
+		WorkspaceID: request.WorkspaceID,
+		ScanRunID:   run.ScanRunID,
+		HostStatus:  run.HostStatus,
+		HostReason:  run.HostReason,
+		HostChange:  hostChange,
+		NameChanges: nameChanges,
 		ScanResults: scannedPorts,
 		Changes:     changedPorts,
 		Host:        scannedHost,
 		PortHistory: portHistory,
 	}
 
-	s.Logger.Debug("Response", zap.Any("response", response))
+	logger.Debug("Response", zap.Any("response", response))
 
 	return response, nil
 }
 
+// recordHistory records an imported scan older than the last scan of its host without comparing it against anything,
+// so neither changes nor the names of the host are updated
+func (s *ScanClient) recordHistory(ctx context.Context, request ScanRequestMapped, scanned *hostScan) (*ScanResponse, error) {
+	scannedHost, run, scannedPorts := scanned.Host, scanned.Run, scanned.Results
+	run.InitiatedBy = request.InitiatedBy
+
+	if err := s.DBClient.UpsertScanResults(ctx, request.WorkspaceID, scannedHost, run, scannedPorts); err != nil {
+		logging.FromContext(ctx, s.Logger).Error("error updating database", zap.Error(err))
+		return nil, apierror.Internal(fmt.Sprintf("Unable to save scan results of host %s", scannedHost.IPAddress), err)
+	}
+
+	return &ScanResponse{
+		WorkspaceID: request.WorkspaceID,
+		ScanRunID:   run.ScanRunID,
+		HostStatus:  run.HostStatus,
+		HostReason:  run.HostReason,
+		Historical:  true,
+		ScanResults: scannedPorts,
+		PortHistory: []*ScanResult{},
+		Host:        scannedHost,
+	}, nil
+}
+
 // comparePorts compares the ports of the newest scan against the last scan and returns a map where the key is the port number and the value is the change type (added, removed)
 func comparePorts(scannedPorts []*ScanResult, portHistory []*ScanResult) map[int]string {
 	// For each port in scannedPorts, we need to get the latest port status from portHistory if it exists
@@ -126,45 +332,223 @@ func comparePorts(scannedPorts []*ScanResult, portHistory []*ScanResult) map[int
 	return changedPorts
 }
 
-// execScanCommand executes an NMap scan command for a single IP address.
-func (s *ScanClient) execScanCommand(ctx context.Context, host Host) (Host, []*ScanResult, error) {
-	var scanParam string
-	var scanResults []*ScanResult
+// compareHostStatus returns the change between the status of a host during its last scan and now, or an empty string
+func compareHostStatus(hostStatus string, lastHostStatus string) string {
+	switch {
+	case lastHostStatus == HostStatusUp && hostStatus == HostStatusDown:
+		return HostChangeDown
+	case lastHostStatus == HostStatusDown && hostStatus == HostStatusUp:
+		return HostChangeUp
+	default:
+		return ""
+	}
+}
 
-	if host.Hostname != "" {
-		scanParam = host.Hostname
-	} else {
-		scanParam = host.IPAddress
+// comparePTRs compares the PTR records reported by the newest scan of a host against the ones current before it
+func comparePTRs(names []*HostName, lastNames []*HostName) []NameChange {
+	ptrs := make(map[string]bool)
+	for _, name := range names {
+		if name.Type == HostNameTypePTR {
+			ptrs[name.Name] = true
+		}
+	}
+
+	lastPTRs := make(map[string]bool)
+	for _, name := range lastNames {
+		if name.Type == HostNameTypePTR && name.Current {
+			lastPTRs[name.Name] = true
+		}
+	}
+
+	var changes []NameChange
+	for name := range ptrs {
+		if !lastPTRs[name] {
+			changes = append(changes, NameChange{Name: name, Change: NameChangePTRAdded})
+		}
+	}
+	for name := range lastPTRs {
+		if !ptrs[name] {
+			changes = append(changes, NameChange{Name: name, Change: NameChangePTRRemoved})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
+
+// uniquePorts returns one scan result per port number out of several lists of results
+func uniquePorts(lists ...[]*ScanResult) []*ScanResult {
+	seen := make(map[int]bool)
+	var results []*ScanResult
+	for _, list := range lists {
+		for _, result := range list {
+			if !seen[result.Port] {
+				seen[result.Port] = true
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+// ResolveHost returns every IPv4 and IPv6 address a hostname resolves to, IPv4 addresses first
+func ResolveHost(ctx context.Context, hostname string) ([]string, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address found for host %s", hostname)
+	}
+	return sortAddresses(addrs), nil
+}
+
+// sortAddresses returns the unique addresses of a lookup, IPv4 addresses first, so the order doesn't depend on DNS round robin
+func sortAddresses(addrs []net.IPAddr) []string {
+	seen := make(map[string]bool)
+	var ips []net.IP
+	for _, addr := range addrs {
+		if !seen[addr.IP.String()] {
+			seen[addr.IP.String()] = true
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	sort.Slice(ips, func(i, j int) bool {
+		if ipv4 := ips[i].To4() != nil; ipv4 != (ips[j].To4() != nil) {
+			return ipv4
+		}
+		return bytes.Compare(ips[i].To16(), ips[j].To16()) < 0
+	})
+
+	addresses := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addresses = append(addresses, ip.String())
 	}
+	return addresses
+}
 
-	cmd := exec.CommandContext(ctx, "nmap", "-p", "0-1000", "--open", "--stats-every", "0", "-oX", "-", "-T5", scanParam)
+// hostScan represents what an nmap scan found out about a single host
+type hostScan struct {
+	Host    Host          // Scanned host, along with the names nmap reported for it
+	Run     *ScanRun      // Run of the scan, without its ID
+	Results []*ScanResult // Open ports of the host
+	Listed  bool          // Whether nmap listed the host, its names are only known then
+}
+
+// execScanCommand executes an NMap scan command with the options of a profile for a single IP address.
+// The address is scanned rather than the hostname, so nmap scans the address it was resolved to.
+func (s *ScanClient) execScanCommand(ctx context.Context, host Host, profile string) (_ *hostScan, err error) {
+	ctx, span := tracer.Start(ctx, "ScanClient.execScanCommand")
+	defer func() { tracing.End(span, err) }()
+
+	logger := logging.FromContext(ctx, s.Logger)
+
+	scanParam := host.IPAddress
+
+	profileArgs, ok := Profiles[profile]
+	if !ok {
+		profile = DefaultProfile
+		profileArgs = Profiles[DefaultProfile]
+	}
+
+	// Build the command through the allowlist so a target can never be read as an nmap option
+	nmapCommand := NewNmapCommand()
+	if err := nmapCommand.AddArgs(profileArgs); err != nil {
+		logger.Error("invalid nmap profile", zap.String("profile", profile), zap.Error(err))
+		return nil, err
+	}
+	if ip := net.ParseIP(scanParam); ip != nil && ip.To4() == nil {
+		if err := nmapCommand.AddFlag("-6"); err != nil {
+			return nil, err
+		}
+	}
+	if err := nmapCommand.AddTarget(scanParam); err != nil {
+		logger.Error("invalid nmap target", zap.String("target", scanParam), zap.Error(err))
+		return nil, apierror.Invalid("ips_or_hostnames", "ip|fqdn", err.Error())
+	}
+	args, err := nmapCommand.Args()
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.StringSlice("nmap.args", args), attribute.String("nmap.profile", profile))
+
+	cmd := exec.CommandContext(ctx, "nmap", args...)
+	start := time.Now()
 	output, err := cmd.CombinedOutput()
+	duration := time.Since(start)
+	metrics.NmapDuration.WithLabelValues(profile).Observe(duration.Seconds())
+	span.SetAttributes(attribute.Float64("nmap.duration_seconds", duration.Seconds()))
 	if err != nil {
-		s.Logger.Error("error running nmap command", zap.Any("host", host))
-		return host, nil, err
+		logger.Error("error running nmap command", zap.Any("host", host))
+		return nil, err
 	}
 
+	scanned, err := parseScanOutput(output, host, profile)
+	if err != nil {
+		logger.Error("error parsing nmap output", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Debug("Parsed nmap output", zap.String("hostStatus", scanned.Run.HostStatus), zap.Int("ports", len(scanned.Results)))
+
+	return scanned, nil
+}
+
+// parseScanOutput parses the XML output of an NMap scan of a single address with a profile.
+// Only the host listed with that address is read, its MAC address, if any, is ignored.
+func parseScanOutput(output []byte, host Host, profile string) (*hostScan, error) {
 	var nmapRun NmapRun
 	if err := xml.Unmarshal(output, &nmapRun); err != nil {
-		s.Logger.Error("error unmarshaling nmap output", zap.Error(err))
-		return host, nil, err
+		return nil, err
 	}
 
-	//var results []ScanResult
 	nmapStartTime, err := strconv.ParseInt(nmapRun.Start, 10, 64)
 	if err != nil {
-		s.Logger.Error("error parsing start time", zap.Error(err))
-		return host, nil, err
+		return nil, err
 	}
 	scanTime := time.Unix(nmapStartTime, 0)
 
-	for _, h := range nmapRun.Hosts {
-		if host.IPAddress != h.Addresses[0].Addr {
-			host.IPAddress = h.Addresses[0].Addr
+	var listed *NmapHost
+	for i, h := range nmapRun.Hosts {
+		if h.IPAddress() == host.IPAddress {
+			listed = &nmapRun.Hosts[i]
+			break
 		}
+	}
 
-		for _, port := range h.Ports {
-			s.Logger.Debug("Port", zap.Any("port", port))
+	// Hosts that are up without open ports aren't listed with --open, only counted
+	run := &ScanRun{Profile: profile, Source: SourceScan, Scanner: ScannerNmap, HostStatus: HostStatusDown, Timestamp: scanTime}
+	if listed != nil {
+		run.HostStatus = HostStatusUp
+		if state := listed.Status.State; state == HostStatusUp || state == HostStatusDown {
+			run.HostStatus = state
+		}
+		run.HostReason = listed.Status.Reason
+	} else if nmapRun.RunStats.Hosts.Up > 0 {
+		run.HostStatus = HostStatusUp
+	} else if nmapRun.RunStats.Hosts.Total == 0 {
+		return nil, apierror.Invalid("ips_or_hostnames", "resolvable", fmt.Sprintf("Host %s doesn't resolve to any address", host.IPAddress))
+	}
+
+	var scanResults []*ScanResult
+	if listed != nil {
+		// nmap only reports the requested hostname when it resolved it, the address was resolved beforehand
+		if host.Hostname != "" {
+			host.Names = append(host.Names, &HostName{Name: host.Hostname, Type: HostNameTypeUser, FirstSeen: scanTime, LastSeen: scanTime, Current: true})
+		}
+		for _, hostname := range listed.Hostnames {
+			if hostname.Type == HostNameTypeUser && hostname.Name == host.Hostname {
+				continue
+			}
+			host.Names = append(host.Names, &HostName{Name: hostname.Name, Type: hostname.Type, FirstSeen: scanTime, LastSeen: scanTime, Current: true})
+		}
+
+		for _, port := range listed.Ports {
 			scanResults = append(scanResults, &ScanResult{
 				IPAddress: host.IPAddress,
 				Timestamp: scanTime,
@@ -172,7 +556,7 @@ func (s *ScanClient) execScanCommand(ctx context.Context, host Host) (Host, []*S
 				Status:    port.State.State,
 			})
 		}
-
 	}
-	return host, scanResults, nil
+
+	return &hostScan{Host: host, Run: run, Results: scanResults, Listed: listed != nil}, nil
 }
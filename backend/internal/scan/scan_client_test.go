@@ -1,7 +1,10 @@
 package scan
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net"
 	"testing"
 	"time"
 )
@@ -231,6 +234,28 @@ func Test_comparePorts(t *testing.T) {
 				443: "removed",
 			},
 		},
+		{
+			name: "Test Case 5: No Open Ports Left",
+			args: args{
+				scannedPorts: nil,
+				portHistory: []*ScanResult{
+					{
+						IPAddress: "1234",
+						Port:      22,
+						Status:    "open",
+					},
+					{
+						IPAddress: "1234",
+						Port:      443,
+						Status:    "open",
+					},
+				},
+			},
+			want: map[int]string{
+				22:  "removed",
+				443: "removed",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -238,3 +263,132 @@ func Test_comparePorts(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseScanOutput(t *testing.T) {
+	const openPorts = `<nmaprun start="1691862400">
+<host><status state="up" reason="syn-ack"/><address addr="34.117.168.233" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="80"><state state="open"/></port><port protocol="tcp" portid="443"><state state="open"/></port></ports>
+</host>
+<runstats><hosts up="1" down="0" total="1"/></runstats>
+</nmaprun>`
+
+	scanned, err := parseScanOutput([]byte(openPorts), Host{IPAddress: "34.117.168.233", Hostname: "www.parkdna.com"}, DefaultProfile)
+	assert.NoError(t, err)
+	assert.Equal(t, "34.117.168.233", scanned.Host.IPAddress)
+	assert.Equal(t, HostStatusUp, scanned.Run.HostStatus)
+	assert.Equal(t, "syn-ack", scanned.Run.HostReason)
+	assert.Equal(t, time.Unix(1691862400, 0), scanned.Run.Timestamp)
+	assert.Len(t, scanned.Results, 2)
+	assert.True(t, scanned.Listed)
+
+	const hostnames = `<nmaprun start="1691862400">
+<host><status state="up" reason="syn-ack"/><address addr="34.117.168.233" addrtype="ipv4"/>
+<hostnames><hostname name="www.parkdna.com" type="user"/><hostname name="233.168.117.34.bc.googleusercontent.com" type="PTR"/></hostnames>
+<ports><port protocol="tcp" portid="443"><state state="open"/></port></ports>
+</host>
+<runstats><hosts up="1" down="0" total="1"/></runstats>
+</nmaprun>`
+	scanned, err = parseScanOutput([]byte(hostnames), Host{IPAddress: "34.117.168.233", Hostname: "www.parkdna.com"}, DefaultProfile)
+	assert.NoError(t, err)
+	assert.Equal(t, []*HostName{
+		{Name: "www.parkdna.com", Type: HostNameTypeUser, FirstSeen: time.Unix(1691862400, 0), LastSeen: time.Unix(1691862400, 0), Current: true},
+		{Name: "233.168.117.34.bc.googleusercontent.com", Type: HostNameTypePTR, FirstSeen: time.Unix(1691862400, 0), LastSeen: time.Unix(1691862400, 0), Current: true},
+	}, scanned.Host.Names)
+
+	// MAC addresses are reported along with the IP address of hosts on the local network
+	const withMAC = `<nmaprun start="1691862400">
+<host><status state="up" reason="arp-response"/><address addr="00:11:22:33:44:55" addrtype="mac"/><address addr="192.168.1.10" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="22"><state state="open"/></port></ports>
+</host>
+<runstats><hosts up="1" down="0" total="1"/></runstats>
+</nmaprun>`
+	scanned, err = parseScanOutput([]byte(withMAC), Host{IPAddress: "192.168.1.10"}, DefaultProfile)
+	assert.NoError(t, err)
+	assert.True(t, scanned.Listed)
+	assert.Equal(t, "192.168.1.10", scanned.Host.IPAddress)
+	assert.Equal(t, "192.168.1.10", scanned.Results[0].IPAddress)
+	assert.Equal(t, "arp-response", scanned.Run.HostReason)
+
+	// Hosts without open ports aren't listed when scanning with --open
+	const noOpenPorts = `<nmaprun start="1691862400"><runstats><hosts up="1" down="0" total="1"/></runstats></nmaprun>`
+	scanned, err = parseScanOutput([]byte(noOpenPorts), Host{IPAddress: "10.0.0.1"}, DefaultProfile)
+	assert.NoError(t, err)
+	assert.Equal(t, HostStatusUp, scanned.Run.HostStatus)
+	assert.Empty(t, scanned.Results)
+	assert.False(t, scanned.Listed)
+
+	const down = `<nmaprun start="1691862400"><runstats><hosts up="0" down="1" total="1"/></runstats></nmaprun>`
+	scanned, err = parseScanOutput([]byte(down), Host{IPAddress: "10.0.0.1"}, DefaultProfile)
+	assert.NoError(t, err)
+	assert.Equal(t, HostStatusDown, scanned.Run.HostStatus)
+	assert.Empty(t, scanned.Results)
+
+	const unresolved = `<nmaprun start="1691862400"><runstats><hosts up="0" down="0" total="0"/></runstats></nmaprun>`
+	_, err = parseScanOutput([]byte(unresolved), Host{IPAddress: "10.0.0.1", Hostname: "nowhere.example.com"}, DefaultProfile)
+	assert.Error(t, err)
+}
+
+func Test_sortAddresses(t *testing.T) {
+	addrs := []net.IPAddr{
+		{IP: net.ParseIP("2001:db8::2")},
+		{IP: net.ParseIP("203.0.113.20")},
+		{IP: net.ParseIP("2001:db8::1")},
+		{IP: net.ParseIP("203.0.113.3")},
+		{IP: net.ParseIP("203.0.113.20")},
+	}
+
+	assert.Equal(t, []string{"203.0.113.3", "203.0.113.20", "2001:db8::1", "2001:db8::2"}, sortAddresses(addrs))
+}
+
+func Test_requestHosts(t *testing.T) {
+	client := NewScanClient(zap.NewNop(), nil)
+
+	// The .invalid TLD never resolves, the hosts can only come from the checked addresses
+	request := ScanRequestMapped{
+		Hostnames: []string{"rebind.invalid"},
+		Addresses: map[string][]string{"rebind.invalid": {"2001:db8::1", "203.0.113.3"}},
+	}
+	hosts, err := client.requestHosts(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, []Host{
+		{IPAddress: "203.0.113.3", Hostname: "rebind.invalid"},
+		{IPAddress: "2001:db8::1", Hostname: "rebind.invalid"},
+	}, hosts)
+
+	hosts, err = client.requestHosts(context.Background(), ScanRequestMapped{IPs: []string{"10.0.0.1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []Host{{IPAddress: "10.0.0.1"}}, hosts)
+}
+
+func Test_comparePTRs(t *testing.T) {
+	names := []*HostName{
+		{Name: "www.example.com", Type: HostNameTypeUser},
+		{Name: "new.example.net", Type: HostNameTypePTR},
+		{Name: "kept.example.net", Type: HostNameTypePTR},
+	}
+	lastNames := []*HostName{
+		{Name: "kept.example.net", Type: HostNameTypePTR, Current: true},
+		{Name: "old.example.net", Type: HostNameTypePTR, Current: true},
+		// Already reported as removed by an earlier scan
+		{Name: "gone.example.net", Type: HostNameTypePTR, Current: false},
+		{Name: "www.example.org", Type: HostNameTypeUser, Current: true},
+	}
+
+	assert.Equal(t, []NameChange{
+		{Name: "new.example.net", Change: NameChangePTRAdded},
+		{Name: "old.example.net", Change: NameChangePTRRemoved},
+	}, comparePTRs(names, lastNames))
+
+	// Names that haven't changed aren't reported
+	assert.Empty(t, comparePTRs(names[2:], lastNames[:1]))
+}
+
+func Test_compareHostStatus(t *testing.T) {
+	assert.Equal(t, HostChangeDown, compareHostStatus(HostStatusDown, HostStatusUp))
+	assert.Equal(t, HostChangeUp, compareHostStatus(HostStatusUp, HostStatusDown))
+	assert.Empty(t, compareHostStatus(HostStatusUp, HostStatusUp))
+	assert.Empty(t, compareHostStatus(HostStatusDown, HostStatusDown))
+
+	// The first scan of a host has nothing to compare against
+	assert.Empty(t, compareHostStatus(HostStatusDown, ""))
+}
@@ -1,16 +1,28 @@
 package internal
 
 import (
+	"backend/internal/apierror"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/policy"
+	"backend/internal/ratelimit"
 	"backend/internal/scan"
+	"backend/internal/scope"
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 	"net"
 	"net/http"
+	"strings"
 )
 
-type ErrorResponse struct {
-	Message string `json:"message"`
+// ScanResponse is a scan response along with the policy violations it produced
+type ScanResponse struct {
+	*scan.ScanResponse
+	Violations   []*policy.Violation `json:"violations,omitempty"`
+	Addresses    []*ScanResponse     `json:"addresses,omitempty"`    // Scans of the other addresses the hostname resolves to
+	Deduplicated bool                `json:"deduplicated,omitempty"` // Result of a scan of the same host that was running or finished within the cooldown
 }
 
 func (s *Server) postScanPortsHandler(c *gin.Context) {
@@ -19,41 +31,186 @@ func (s *Server) postScanPortsHandler(c *gin.Context) {
 	var scanRequest scan.ScanRequest
 	if err := c.ShouldBindJSON(&scanRequest); err != nil {
 		s.Logger.Error("unable to bind json", zap.Error(err))
-		c.JSON(http.StatusBadRequest, c.Error(err))
+		abortWithError(c, apierror.Binding(err))
 		return
 	}
 
-	var req scan.ScanRequestMapped
-	for _, value := range scanRequest.IPsOrHostnames {
+	if err := validate.Struct(scanRequest); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	req := mapScanRequest(scanRequest.IPsOrHostnames, scanRequest.Profile)
+	req.WorkspaceID = getWorkspaceID(c)
+	if principal := getPrincipal(c); principal != nil {
+		req.InitiatedBy = principal.String()
+	}
+
+	s.Logger.Debug("request received", zap.Any("request", req))
+	setAuditTarget(c, strings.Join(append(append([]string{}, req.IPs...), req.Hostnames...), ","))
+
+	scanResponse, err := s.runScan(ctx, req)
+	var scopeErr *scope.Error
+	if errors.As(err, &scopeErr) {
+		s.Logger.Warn("target out of scope", zap.String("target", scopeErr.Target), zap.String("reason", scopeErr.Reason))
+		abortWithError(c, apierror.Wrap(apierror.CodeOutOfScope, scopeErr))
+		return
+	}
+	if errors.Is(err, ratelimit.ErrQueueFull) {
+		abortTooManyRequests(c, ratelimit.QueueRetryAfter, err)
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to scan ports", zap.Error(err))
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scanResponse)
+}
+
+// mapScanRequest splits a list of targets into IPs and hostnames
+func mapScanRequest(ipsOrHostnames []string, profile string) scan.ScanRequestMapped {
+	req := scan.ScanRequestMapped{Profile: profile}
+	for _, value := range ipsOrHostnames {
 		if net.ParseIP(value) != nil {
 			req.IPs = append(req.IPs, value)
 		} else {
 			req.Hostnames = append(req.Hostnames, value)
 		}
 	}
+	return req
+}
 
-	// Check that at least one of the fields is not empty
-	if len(req.IPs) == 0 && len(req.Hostnames) == 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "At least one IP or hostname is required"})
-		return
+// runScan checks the requested host is in scope and scans it, unless a scan of the same host is running
+// or finished within the cooldown, in which case that scan's result is returned instead
+func (s *Server) runScan(ctx context.Context, req scan.ScanRequestMapped) (*ScanResponse, error) {
+	// Resolve and check every target before anything is handed to nmap, hostnames are scanned at the checked addresses
+	req.Addresses = make(map[string][]string, len(req.Hostnames))
+	for _, target := range append(append([]string{}, req.IPs...), req.Hostnames...) {
+		addresses, err := s.Scope.Check(ctx, target)
+		if err != nil {
+			metrics.ScansTotal.WithLabelValues(metrics.OutcomeOutOfScope).Inc()
+			return nil, err
+		}
+		if net.ParseIP(target) == nil {
+			req.Addresses[target] = addresses
+		}
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		validationErrors := err.(validator.ValidationErrors)
-		s.Logger.Error("validation error", zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: validationErrors.Error()})
-		return
+	if s.ScanGroup == nil {
+		response, err := s.executeScan(ctx, req)
+		countScan(err, false)
+		return response, err
 	}
 
-	s.Logger.Debug("request received", zap.Any("request", req))
+	// The scan carries on for the other callers waiting on it if this one goes away
+	response, shared, err := s.ScanGroup.Do(ctx, scanKey(req), func() (*ScanResponse, error) {
+		return s.executeScan(context.WithoutCancel(ctx), req)
+	})
+	countScan(err, shared)
+	if err != nil || !shared {
+		return response, err
+	}
+
+	deduplicated := *response
+	deduplicated.Deduplicated = true
+	return &deduplicated, nil
+}
+
+// countScan counts a scan by its outcome
+func countScan(err error, shared bool) {
+	outcome := metrics.OutcomeSuccess
+	switch {
+	case errors.Is(err, ratelimit.ErrQueueFull):
+		outcome = metrics.OutcomeRejected
+	case err != nil:
+		outcome = metrics.OutcomeFailed
+	case shared:
+		outcome = metrics.OutcomeDeduplicated
+	}
+	metrics.ScansTotal.WithLabelValues(outcome).Inc()
+}
 
-	scanResponse, err := s.ScanClient.ScanForOpenPorts(ctx, req)
+// scanKey identifies the scans of the same host with the same profile in a workspace
+func scanKey(req scan.ScanRequestMapped) string {
+	target := ""
+	if len(req.IPs) > 0 {
+		target = req.IPs[0]
+	} else if len(req.Hostnames) > 0 {
+		target = req.Hostnames[0]
+	}
+
+	profile := req.Profile
+	if profile == "" {
+		profile = scan.DefaultProfile
+	}
+
+	return req.WorkspaceID + "|" + profile + "|" + strings.ToLower(target)
+}
+
+// executeScan scans a host, evaluates the port policies against the results and notifies the subscribers of any changes.
+// The response describes the first address of the host, the other addresses a hostname resolves to are listed under it.
+func (s *Server) executeScan(ctx context.Context, req scan.ScanRequestMapped) (*ScanResponse, error) {
+	scanResponses, err := s.ScanClient.ScanForOpenPorts(ctx, req)
 	if err != nil {
-		s.Logger.Error("unable to scan ports", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, c.Error(err))
+		return nil, err
+	}
+
+	var response *ScanResponse
+	for _, scanResponse := range scanResponses {
+		addressResponse := s.processScan(ctx, scanResponse)
+		if response == nil {
+			response = addressResponse
+		} else {
+			response.Addresses = append(response.Addresses, addressResponse)
+		}
+	}
+
+	return response, nil
+}
+
+// processScan evaluates the port policies against the results of the scan of an address and notifies the subscribers of any changes.
+// The scan is already recorded, so its changes are notified even when the policies can't be evaluated: the next scan
+// compares against it and would never report them again.
+func (s *Server) processScan(ctx context.Context, scanResponse *scan.ScanResponse) *ScanResponse {
+	violations, err := s.PolicyClient.EvaluateScan(ctx, scanResponse)
+	if err != nil {
+		logging.FromContext(ctx, s.Logger).Error("unable to evaluate port policies", zap.String("ip_address", scanResponse.Host.IPAddress), zap.Error(err))
+	}
+
+	s.WebhookClient.NotifyChanges(ctx, scanResponse)
+
+	if s.EmailClient != nil {
+		s.EmailClient.NotifyScan(ctx, scanResponse, violations)
+	}
+
+	if s.SyslogClient != nil {
+		go s.SyslogClient.EmitScan(scanResponse)
+	}
+
+	return &ScanResponse{
+		ScanResponse: scanResponse,
+		Violations:   violations,
+	}
+}
+
+func (s *Server) getScanRunsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ipAddress := c.Query("ip_address")
+	if ipAddress != "" && net.ParseIP(ipAddress) == nil {
+		abortWithError(c, apierror.Invalid("ip_address", "ip", "A valid IP address is required"))
 		return
 	}
 
-	c.JSON(http.StatusOK, scanResponse)
+	runs, err := s.DBClient.QueryScanRuns(ctx, getWorkspaceID(c), ipAddress)
+	if err != nil {
+		s.Logger.Error("unable to query scan runs", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query scan runs", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
 }
@@ -0,0 +1,235 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout MySQL uses for timestamp columns
+const timeLayout = "2006-01-02 15:04:05"
+
+// ErrScheduleNotFound is returned when a schedule does not exist.
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// IDBClient is an interface that defines the methods for interacting with the Schedules table.
+type IDBClient interface {
+	InsertSchedule(ctx context.Context, schedule *Schedule) error
+	QuerySchedules(ctx context.Context, workspaceID string) ([]*Schedule, error)
+	QueryEnabledSchedules(ctx context.Context) ([]*Schedule, error)
+	QuerySchedule(ctx context.Context, workspaceID string, scheduleID string) (*Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule *Schedule) error
+	ClaimScheduleRun(ctx context.Context, scheduleID string, dueAt, now, nextRunAt, staleBefore time.Time) (bool, error)
+	SkipScheduleRun(ctx context.Context, scheduleID string, dueAt, nextRunAt time.Time) (bool, error)
+	RenewScheduleRun(ctx context.Context, scheduleID string, now time.Time) error
+	ReleaseScheduleRun(ctx context.Context, scheduleID string) error
+	DeleteSchedule(ctx context.Context, workspaceID string, scheduleID string) error
+}
+
+// DBClient is a struct that implements the IDBClient interface.
+type DBClient struct {
+	DB     *sql.DB
+	Logger *zap.Logger
+}
+
+// NewDBClient creates a new instance of DBClient sharing an existing database connection.
+func NewDBClient(conn *sql.DB, logger *zap.Logger) *DBClient {
+	return &DBClient{
+		DB:     conn,
+		Logger: logger,
+	}
+}
+
+const selectSchedules = `SELECT schedule_id, workspace_id, name, targets, profile, cron, ` + "`interval`" + `, enabled, last_run_at, next_run_at, created_at FROM Schedules`
+
+// InsertSchedule inserts a schedule in the database and sets its ID and creation time.
+func (db *DBClient) InsertSchedule(ctx context.Context, schedule *Schedule) error {
+	schedule.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	queryString := "INSERT INTO Schedules (workspace_id, name, targets, profile, cron, `interval`, enabled, next_run_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := db.DB.ExecContext(ctx, queryString, schedule.WorkspaceID, schedule.Name, strings.Join(schedule.Targets, ","), schedule.Profile,
+		schedule.Cron, schedule.Interval, schedule.IsEnabled(), schedule.NextRunAt, schedule.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	schedule.ScheduleID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// QuerySchedules queries the database for all the schedules of a workspace.
+func (db *DBClient) QuerySchedules(ctx context.Context, workspaceID string) ([]*Schedule, error) {
+	return db.querySchedules(ctx, selectSchedules+` WHERE workspace_id = ? ORDER BY schedule_id`, workspaceID)
+}
+
+// QueryEnabledSchedules queries the database for the enabled schedules of every workspace.
+func (db *DBClient) QueryEnabledSchedules(ctx context.Context) ([]*Schedule, error) {
+	return db.querySchedules(ctx, selectSchedules+` WHERE enabled = TRUE ORDER BY schedule_id`)
+}
+
+// querySchedules runs a query returning schedules
+func (db *DBClient) querySchedules(ctx context.Context, queryString string, args ...any) ([]*Schedule, error) {
+	rows, err := db.DB.QueryContext(ctx, queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	schedules := []*Schedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// QuerySchedule queries the database for a single schedule of a workspace.
+func (db *DBClient) QuerySchedule(ctx context.Context, workspaceID string, scheduleID string) (*Schedule, error) {
+	rows, err := db.DB.QueryContext(ctx, selectSchedules+` WHERE workspace_id = ? AND schedule_id = ?`, workspaceID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrScheduleNotFound
+	}
+
+	return scanSchedule(rows)
+}
+
+// UpdateSchedule replaces the settings of a schedule.
+func (db *DBClient) UpdateSchedule(ctx context.Context, schedule *Schedule) error {
+	queryString := "UPDATE Schedules SET name = ?, targets = ?, profile = ?, cron = ?, `interval` = ?, enabled = ?, next_run_at = ? WHERE workspace_id = ? AND schedule_id = ?"
+	res, err := db.DB.ExecContext(ctx, queryString, schedule.Name, strings.Join(schedule.Targets, ","), schedule.Profile,
+		schedule.Cron, schedule.Interval, schedule.IsEnabled(), schedule.NextRunAt, schedule.WorkspaceID, schedule.ScheduleID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// MySQL reports zero affected rows when nothing changed, so check the schedule exists
+	if affected == 0 {
+		_, err = db.QuerySchedule(ctx, schedule.WorkspaceID, schedule.ScheduleID)
+		return err
+	}
+
+	return nil
+}
+
+// ClaimScheduleRun claims the run of a schedule due at dueAt, records it as running since now and moves the schedule
+// to its next run. It returns false when the run was already claimed, by another scheduler sharing the database,
+// or when the previous run is still going, that is its lease was renewed after staleBefore.
+func (db *DBClient) ClaimScheduleRun(ctx context.Context, scheduleID string, dueAt, now, nextRunAt, staleBefore time.Time) (bool, error) {
+	queryString := `UPDATE Schedules SET running_since = ?, last_run_at = ?, next_run_at = ?
+		WHERE schedule_id = ? AND enabled AND next_run_at = ? AND (running_since IS NULL OR running_since < ?)`
+	return db.execAffected(ctx, queryString, now, now, nextRunAt, scheduleID, dueAt, staleBefore)
+}
+
+// SkipScheduleRun moves a schedule whose run due at dueAt wasn't claimed to its next run.
+// It returns false when another scheduler already moved it on.
+func (db *DBClient) SkipScheduleRun(ctx context.Context, scheduleID string, dueAt, nextRunAt time.Time) (bool, error) {
+	return db.execAffected(ctx, `UPDATE Schedules SET next_run_at = ? WHERE schedule_id = ? AND next_run_at = ?`, nextRunAt, scheduleID, dueAt)
+}
+
+// RenewScheduleRun renews the lease of a running schedule.
+func (db *DBClient) RenewScheduleRun(ctx context.Context, scheduleID string, now time.Time) error {
+	_, err := db.DB.ExecContext(ctx, `UPDATE Schedules SET running_since = ? WHERE schedule_id = ? AND running_since IS NOT NULL`, now, scheduleID)
+	return err
+}
+
+// ReleaseScheduleRun releases the lease of a schedule once its run is over.
+func (db *DBClient) ReleaseScheduleRun(ctx context.Context, scheduleID string) error {
+	_, err := db.DB.ExecContext(ctx, `UPDATE Schedules SET running_since = NULL WHERE schedule_id = ?`, scheduleID)
+	return err
+}
+
+// execAffected runs a statement and reports whether it changed a row
+func (db *DBClient) execAffected(ctx context.Context, queryString string, args ...any) (bool, error) {
+	res, err := db.DB.ExecContext(ctx, queryString, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// DeleteSchedule deletes a schedule of a workspace from the database.
+func (db *DBClient) DeleteSchedule(ctx context.Context, workspaceID string, scheduleID string) error {
+	res, err := db.DB.ExecContext(ctx, `DELETE FROM Schedules WHERE workspace_id = ? AND schedule_id = ?`, workspaceID, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+// scanSchedule scans the current row into a Schedule
+func scanSchedule(rows *sql.Rows) (*Schedule, error) {
+	var schedule Schedule
+	var targets, nextRunAtStr, createdAtStr string
+	var lastRunAtStr sql.NullString
+	var enabled bool
+	err := rows.Scan(&schedule.ScheduleID, &schedule.WorkspaceID, &schedule.Name, &targets, &schedule.Profile, &schedule.Cron, &schedule.Interval,
+		&enabled, &lastRunAtStr, &nextRunAtStr, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.Enabled = &enabled
+
+	schedule.Targets = strings.Split(targets, ",")
+
+	if lastRunAtStr.Valid {
+		lastRunAt, err := time.Parse(timeLayout, lastRunAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+		schedule.LastRunAt = &lastRunAt
+	}
+	if schedule.NextRunAt, err = time.Parse(timeLayout, nextRunAtStr); err != nil {
+		return nil, err
+	}
+	if schedule.CreatedAt, err = time.Parse(timeLayout, createdAtStr); err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
@@ -0,0 +1,24 @@
+package schedule
+
+import "time"
+
+// Schedule represents a recurring scan of a list of targets.
+// A schedule runs either on a cron expression or at a fixed interval.
+type Schedule struct {
+	ScheduleID  string     `db:"schedule_id" json:"schedule_id,omitempty"`
+	WorkspaceID string     `db:"workspace_id" json:"workspace_id"`
+	Name        string     `db:"name" json:"name" validate:"required,max=255"`
+	Targets     []string   `db:"targets" json:"targets" validate:"required,min=1,dive,ip|fqdn"`                  // IPs or hostnames to scan
+	Profile     string     `db:"profile" json:"profile,omitempty" validate:"omitempty,oneof=default quick full"` // Scan profile
+	Cron        string     `db:"cron" json:"cron,omitempty" validate:"required_without=Interval,excluded_with=Interval"`
+	Interval    string     `db:"interval" json:"interval,omitempty" validate:"required_without=Cron,excluded_with=Cron"` // Go duration, e.g. 6h
+	Enabled     *bool      `db:"enabled" json:"enabled"`                                                                 // Whether the schedule runs, defaults to true when left out
+	LastRunAt   *time.Time `db:"last_run_at" json:"last_run_at,omitempty"`
+	NextRunAt   time.Time  `db:"next_run_at" json:"next_run_at"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// IsEnabled returns whether the schedule runs, a schedule created without setting Enabled does
+func (s *Schedule) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
@@ -0,0 +1,225 @@
+package schedule
+
+import (
+	"backend/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracer creates the spans of the schedule package
+var tracer = otel.Tracer("backend/internal/schedule")
+
+const (
+	// DefaultPollInterval is how often the scheduler looks for due schedules
+	DefaultPollInterval = 15 * time.Second
+	// DefaultMaxJitter is the upper bound of the random delay added before every run
+	DefaultMaxJitter = 30 * time.Second
+	// minInterval is the shortest interval a schedule may run at
+	minInterval = time.Minute
+	// leaseRenewInterval is how often a running schedule renews its lease
+	leaseRenewInterval = time.Minute
+	// leaseTimeout is how old a lease gets before its run is considered dead, e.g. after a crash, and the schedule may run again
+	leaseTimeout = 5 * leaseRenewInterval
+)
+
+// RunFunc runs the scans of a schedule
+type RunFunc func(ctx context.Context, schedule *Schedule) error
+
+// Scheduler represents a background runner for the stored schedules.
+// Schedulers sharing a database, one per replica, claim every run in it so each run happens once.
+type Scheduler struct {
+	Logger       *zap.Logger   // Logger
+	DBClient     IDBClient     // Database client
+	Run          RunFunc       // Runs the scans of a due schedule
+	PollInterval time.Duration // How often to look for due schedules
+	MaxJitter    time.Duration // Upper bound of the random delay added before every run
+
+	mu       sync.Mutex // Guards lastPoll
+	lastPoll time.Time  // When the schedules were last looked up
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(logger *zap.Logger, DBClient IDBClient, run RunFunc) *Scheduler {
+	return &Scheduler{
+		Logger:       logger,
+		DBClient:     DBClient,
+		Run:          run,
+		PollInterval: DefaultPollInterval,
+		MaxJitter:    DefaultMaxJitter,
+	}
+}
+
+// Start runs due schedules in the background until the context is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.markPolled(time.Now())
+
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDue(ctx)
+		}
+	}
+}
+
+// runDue starts every enabled schedule whose next run is due and claimed by this scheduler, skipping the ones still running
+func (s *Scheduler) runDue(ctx context.Context) {
+	schedules, err := s.DBClient.QueryEnabledSchedules(ctx)
+	if err != nil {
+		s.Logger.Error("error querying schedules", zap.Error(err))
+		return
+	}
+
+	now := time.Now().UTC()
+	s.markPolled(now)
+	for _, schedule := range schedules {
+		if !schedule.IsEnabled() || schedule.NextRunAt.After(now) {
+			continue
+		}
+
+		nextRunAt, err := NextRun(schedule, now)
+		if err != nil {
+			s.Logger.Error("error computing next run", zap.String("schedule_id", schedule.ScheduleID), zap.Error(err))
+			continue
+		}
+
+		claimed, err := s.DBClient.ClaimScheduleRun(ctx, schedule.ScheduleID, schedule.NextRunAt, now, nextRunAt, now.Add(-leaseTimeout))
+		if err != nil {
+			s.Logger.Error("error claiming schedule run", zap.String("schedule_id", schedule.ScheduleID), zap.Error(err))
+			continue
+		}
+
+		// Either another scheduler claimed the run, or the previous run is still going and this slot is skipped
+		if !claimed {
+			skipped, err := s.DBClient.SkipScheduleRun(ctx, schedule.ScheduleID, schedule.NextRunAt, nextRunAt)
+			if err != nil {
+				s.Logger.Error("error updating schedule", zap.String("schedule_id", schedule.ScheduleID), zap.Error(err))
+			}
+			if skipped {
+				s.Logger.Warn("skipping overlapping schedule run", zap.String("schedule_id", schedule.ScheduleID))
+			}
+			continue
+		}
+
+		go s.run(ctx, schedule)
+	}
+}
+
+// run waits a random jitter then runs a schedule, holding its lease until it is done
+func (s *Scheduler) run(ctx context.Context, schedule *Schedule) {
+	// The lease is released on shutdown too, so a restart doesn't wait for it to time out
+	defer func() {
+		if err := s.DBClient.ReleaseScheduleRun(context.WithoutCancel(ctx), schedule.ScheduleID); err != nil {
+			s.Logger.Error("error releasing schedule run", zap.String("schedule_id", schedule.ScheduleID), zap.Error(err))
+		}
+	}()
+
+	renewCtx, stopRenewing := context.WithCancel(ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		s.renewLease(renewCtx, schedule.ScheduleID)
+	}()
+	// A renewal still in flight must not take the lease back after it is released
+	defer func() {
+		stopRenewing()
+		<-renewed
+	}()
+
+	// Spread the runs so schedules sharing a slot don't all start nmap at once
+	if s.MaxJitter > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(rand.Int63n(int64(s.MaxJitter)))):
+		}
+	}
+
+	s.Logger.Info("running schedule", zap.String("schedule_id", schedule.ScheduleID), zap.Strings("targets", schedule.Targets))
+
+	// Every run starts its own trace, the scans it launches are children of it
+	ctx, span := tracer.Start(ctx, "Scheduler.run", trace.WithNewRoot(), trace.WithAttributes(
+		attribute.String("schedule.id", schedule.ScheduleID),
+		attribute.String("schedule.workspace_id", schedule.WorkspaceID),
+		attribute.StringSlice("schedule.targets", schedule.Targets),
+	))
+	err := s.Run(ctx, schedule)
+	tracing.End(span, err)
+	if err != nil {
+		s.Logger.Error("error running schedule", zap.String("schedule_id", schedule.ScheduleID), zap.Error(err))
+	}
+}
+
+// renewLease keeps the lease of a running schedule from timing out until the context ends
+func (s *Scheduler) renewLease(ctx context.Context, scheduleID string) {
+	ticker := time.NewTicker(leaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.DBClient.RenewScheduleRun(ctx, scheduleID, time.Now().UTC()); err != nil {
+				s.Logger.Error("error renewing schedule run", zap.String("schedule_id", scheduleID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// NextRun returns the first time after now a schedule is due, based on its cron expression or interval
+func NextRun(schedule *Schedule, now time.Time) (time.Time, error) {
+	if schedule.Cron != "" {
+		cronSchedule, err := cron.ParseStandard(schedule.Cron)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid cron expression: %w", err)
+		}
+		return cronSchedule.Next(now).UTC().Truncate(time.Second), nil
+	}
+
+	interval, err := time.ParseDuration(schedule.Interval)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid interval: %w", err)
+	}
+	if interval < minInterval {
+		return time.Time{}, fmt.Errorf("interval must be at least %s", minInterval)
+	}
+
+	return now.Add(interval).UTC().Truncate(time.Second), nil
+}
+
+// markPolled records that the schedules were looked up
+func (s *Scheduler) markPolled(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastPoll = now
+}
+
+// Check returns an error when the scheduler isn't running or hasn't looked up the schedules for several poll intervals
+func (s *Scheduler) Check(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastPoll.IsZero() {
+		return errors.New("scheduler is not running")
+	}
+	if since := now.Sub(s.lastPoll); since > 3*s.PollInterval {
+		return fmt.Errorf("scheduler last polled %s ago", since.Truncate(time.Second))
+	}
+	return nil
+}
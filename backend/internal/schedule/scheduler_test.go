@@ -0,0 +1,170 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNextRun(t *testing.T) {
+	now := time.Date(2021, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "Test Case 1: Hourly Cron",
+			schedule: &Schedule{Cron: "0 * * * *"},
+			want:     time.Date(2021, 1, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Test Case 2: Daily Cron Descriptor",
+			schedule: &Schedule{Cron: "@daily"},
+			want:     time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Test Case 3: Interval",
+			schedule: &Schedule{Interval: "6h"},
+			want:     time.Date(2021, 1, 1, 16, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Test Case 4: Invalid Cron",
+			schedule: &Schedule{Cron: "every hour"},
+			wantErr:  true,
+		},
+		{
+			name:     "Test Case 5: Interval Too Short",
+			schedule: &Schedule{Interval: "10s"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NextRun(tt.schedule, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// scheduleStore keeps schedules in memory and claims their runs like the Schedules table does
+type scheduleStore struct {
+	IDBClient
+	mu           sync.Mutex
+	schedule     Schedule
+	runningSince *time.Time
+	released     chan struct{}
+}
+
+func (s *scheduleStore) QueryEnabledSchedules(context.Context) ([]*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule := s.schedule
+	return []*Schedule{&schedule}, nil
+}
+
+func (s *scheduleStore) ClaimScheduleRun(_ context.Context, _ string, dueAt, now, nextRunAt, staleBefore time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.schedule.NextRunAt.Equal(dueAt) || (s.runningSince != nil && !s.runningSince.Before(staleBefore)) {
+		return false, nil
+	}
+	s.runningSince = &now
+	s.schedule.LastRunAt = &now
+	s.schedule.NextRunAt = nextRunAt
+	return true, nil
+}
+
+func (s *scheduleStore) SkipScheduleRun(_ context.Context, _ string, dueAt, nextRunAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.schedule.NextRunAt.Equal(dueAt) {
+		return false, nil
+	}
+	s.schedule.NextRunAt = nextRunAt
+	return true, nil
+}
+
+func (s *scheduleStore) ReleaseScheduleRun(context.Context, string) error {
+	s.mu.Lock()
+	s.runningSince = nil
+	s.mu.Unlock()
+	close(s.released)
+	return nil
+}
+
+// due makes the schedule due again
+func (s *scheduleStore) due() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedule.NextRunAt = time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+}
+
+func TestScheduler_runDueClaimsRuns(t *testing.T) {
+	store := &scheduleStore{schedule: Schedule{ScheduleID: "1", Interval: "1h"}, released: make(chan struct{})}
+	store.due()
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	run := func(context.Context, *Schedule) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}
+
+	// Two replicas polling the same database run the schedule once
+	replicas := []*Scheduler{NewScheduler(zap.NewNop(), store, run), NewScheduler(zap.NewNop(), store, run)}
+	for _, replica := range replicas {
+		replica.MaxJitter = 0
+		replica.runDue(context.Background())
+	}
+
+	// A slot coming up while the run is still going is skipped by every replica
+	store.due()
+	for _, replica := range replicas {
+		replica.runDue(context.Background())
+	}
+
+	close(release)
+	<-store.released
+	assert.Equal(t, int32(1), runs.Load())
+	assert.Nil(t, store.runningSince, "the lease should be released once the run is over")
+	assert.True(t, store.schedule.NextRunAt.After(time.Now()), "the skipped slot should move the schedule on")
+}
+
+func TestScheduler_Check(t *testing.T) {
+	now := time.Date(2021, 1, 1, 10, 30, 0, 0, time.UTC)
+	scheduler := NewScheduler(zap.NewNop(), nil, nil)
+
+	assert.EqualError(t, scheduler.Check(now), "scheduler is not running")
+
+	scheduler.markPolled(now)
+	assert.NoError(t, scheduler.Check(now.Add(scheduler.PollInterval)))
+	assert.EqualError(t, scheduler.Check(now.Add(time.Minute)), "scheduler last polled 1m0s ago")
+}
+
+func TestSchedule_IsEnabled(t *testing.T) {
+	var schedule Schedule
+	assert.NoError(t, json.Unmarshal([]byte(`{"name": "nightly", "targets": ["10.0.0.1"], "interval": "24h"}`), &schedule))
+	assert.True(t, schedule.IsEnabled(), "a schedule created without enabled should run")
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"name": "nightly", "targets": ["10.0.0.1"], "interval": "24h", "enabled": false}`), &schedule))
+	assert.False(t, schedule.IsEnabled())
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/audit"
+	"backend/internal/schedule"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) postScheduleHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	sched, ok := s.bindSchedule(c)
+	if !ok {
+		return
+	}
+
+	if err := s.Scheduler.DBClient.InsertSchedule(ctx, sched); err != nil {
+		s.Logger.Error("unable to insert schedule", zap.Error(err))
+		abortWithError(c, storeError("Unable to create schedule", err))
+		return
+	}
+
+	setAuditTarget(c, sched.ScheduleID)
+	c.JSON(http.StatusCreated, sched)
+}
+
+func (s *Server) getSchedulesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	schedules, err := s.Scheduler.DBClient.QuerySchedules(ctx, getWorkspaceID(c))
+	if err != nil {
+		s.Logger.Error("unable to query schedules", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query schedules", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+func (s *Server) getScheduleHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	sched, err := s.Scheduler.DBClient.QuerySchedule(ctx, getWorkspaceID(c), c.Param("id"))
+	if errors.Is(err, schedule.ErrScheduleNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to query schedule", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query schedule", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, sched)
+}
+
+func (s *Server) putScheduleHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	sched, ok := s.bindSchedule(c)
+	if !ok {
+		return
+	}
+	sched.ScheduleID = c.Param("id")
+
+	err := s.Scheduler.DBClient.UpdateSchedule(ctx, sched)
+	if errors.Is(err, schedule.ErrScheduleNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to update schedule", zap.Error(err))
+		abortWithError(c, storeError("Unable to update schedule", err))
+		return
+	}
+
+	sched, err = s.Scheduler.DBClient.QuerySchedule(ctx, sched.WorkspaceID, sched.ScheduleID)
+	if err != nil {
+		s.Logger.Error("unable to query schedule", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query schedule", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, sched)
+}
+
+func (s *Server) deleteScheduleHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.Scheduler.DBClient.DeleteSchedule(ctx, getWorkspaceID(c), c.Param("id"))
+	if errors.Is(err, schedule.ErrScheduleNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to delete schedule", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to delete schedule", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// bindSchedule binds and validates a schedule from the request body and computes its first run.
+// It writes the error response and returns false when the schedule is invalid.
+func (s *Server) bindSchedule(c *gin.Context) (*schedule.Schedule, bool) {
+	var sched schedule.Schedule
+	if err := c.ShouldBindJSON(&sched); err != nil {
+		s.Logger.Error("unable to bind json", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return nil, false
+	}
+
+	if err := validate.Struct(sched); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return nil, false
+	}
+
+	nextRunAt, err := schedule.NextRun(&sched, time.Now().UTC())
+	if err != nil {
+		field := "cron"
+		if sched.Interval != "" {
+			field = "interval"
+		}
+		abortWithError(c, apierror.Invalid(field, "format", err.Error()))
+		return nil, false
+	}
+	sched.NextRunAt = nextRunAt
+	sched.WorkspaceID = getWorkspaceID(c)
+
+	// A schedule left without "enabled" runs, like the column default
+	if sched.Enabled == nil {
+		enabled := true
+		sched.Enabled = &enabled
+	}
+
+	return &sched, true
+}
+
+// runSchedule scans every target of a schedule, one scan per target
+func (s *Server) runSchedule(ctx context.Context, sched *schedule.Schedule) error {
+	return s.scanTargets(ctx, sched.WorkspaceID, sched.Targets, sched.Profile, "schedule:"+sched.ScheduleID)
+}
+
+// scanTargets scans a list of targets one after the other on behalf of a caller, recording every scan in the audit log.
+// A failed scan doesn't stop the others, their errors are returned together.
+func (s *Server) scanTargets(ctx context.Context, workspaceID string, targets []string, profile string, initiatedBy string) error {
+	var errs []error
+	for _, target := range targets {
+		req := mapScanRequest([]string{target}, profile)
+		req.InitiatedBy = initiatedBy
+		req.WorkspaceID = workspaceID
+
+		s.recordAudit(ctx, &audit.Entry{
+			WorkspaceID: workspaceID,
+			Actor:       initiatedBy,
+			Action:      audit.ActionScanStart,
+			Target:      target,
+		})
+
+		if _, err := s.runScan(ctx, req); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,164 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DefaultDeniedCIDRs are never scanned, whatever the configuration: loopback, unspecified, link-local
+// (which covers the cloud metadata address 169.254.169.254) and the AWS IPv6 metadata address
+var DefaultDeniedCIDRs = []string{
+	"127.0.0.0/8",
+	"0.0.0.0/8",
+	"169.254.0.0/16",
+	"::1/128",
+	"::/128",
+	"fe80::/10",
+	"fd00:ec2::254/128",
+}
+
+// Resolver resolves hostnames into IP addresses
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Error is returned when a target is outside of the scan scope
+type Error struct {
+	Target string // Target that was rejected
+	Reason string // Why the target was rejected
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("target %s is out of scope: %s", e.Target, e.Reason)
+}
+
+// Scope represents the policy deciding which targets may be scanned.
+// When no allowed CIDRs or domains are configured every target that isn't denied is in scope.
+type Scope struct {
+	AllowedCIDRs   []*net.IPNet // Networks that may be scanned
+	AllowedDomains []string     // Domains, and their subdomains, that may be scanned
+	DeniedCIDRs    []*net.IPNet // Networks that may never be scanned
+	Resolver       Resolver     // Resolver used to check what a hostname points to
+}
+
+// NewScope creates a new Scope from lists of CIDRs and domains, the default denied CIDRs are always included
+func NewScope(allowedCIDRs, allowedDomains, deniedCIDRs []string) (*Scope, error) {
+	allowed, err := parseCIDRs(allowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	denied, err := parseCIDRs(append(append([]string{}, DefaultDeniedCIDRs...), deniedCIDRs...))
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []string
+	for _, domain := range allowedDomains {
+		domains = append(domains, strings.ToLower(strings.Trim(domain, ".")))
+	}
+
+	return &Scope{
+		AllowedCIDRs:   allowed,
+		AllowedDomains: domains,
+		DeniedCIDRs:    denied,
+		Resolver:       net.DefaultResolver,
+	}, nil
+}
+
+// Check resolves a target and returns an *Error if it, or any address it resolves to, is out of scope.
+// The checked addresses are returned so the target is scanned at them, a second lookup could answer differently.
+func (s *Scope) Check(ctx context.Context, target string) ([]string, error) {
+	var ips []net.IP
+	domainAllowed := false
+	if ip := net.ParseIP(target); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		domainAllowed = s.domainAllowed(target)
+		if len(s.AllowedDomains) > 0 && len(s.AllowedCIDRs) == 0 && !domainAllowed {
+			return nil, &Error{Target: target, Reason: "hostname is not in an allowed domain"}
+		}
+
+		addrs, err := s.Resolver.LookupIPAddr(ctx, target)
+		if err != nil {
+			return nil, &Error{Target: target, Reason: "hostname could not be resolved"}
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+		if len(ips) == 0 {
+			return nil, &Error{Target: target, Reason: "hostname could not be resolved"}
+		}
+	}
+
+	restricted := len(s.AllowedCIDRs) > 0 || len(s.AllowedDomains) > 0
+	for _, ip := range ips {
+		// Denied ranges win over everything, so an allowed hostname can't resolve into them
+		if network := contains(s.DeniedCIDRs, ip); network != nil {
+			return nil, &Error{Target: target, Reason: fmt.Sprintf("address %s is in the denied range %s", ip, network)}
+		}
+
+		if restricted && !domainAllowed && contains(s.AllowedCIDRs, ip) == nil {
+			return nil, &Error{Target: target, Reason: fmt.Sprintf("address %s is not in an allowed range", ip)}
+		}
+	}
+
+	addresses := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addresses = append(addresses, ip.String())
+	}
+	return addresses, nil
+}
+
+// domainAllowed checks if a hostname is one of the allowed domains or one of their subdomains
+func (s *Scope) domainAllowed(hostname string) bool {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	for _, domain := range s.AllowedDomains {
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns the first network containing the IP, or nil
+func contains(networks []*net.IPNet, ip net.IP) *net.IPNet {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return network
+		}
+	}
+	return nil
+}
+
+// parseCIDRs parses a list of CIDRs, a bare IP is treated as a single address network
+func parseCIDRs(values []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			value = fmt.Sprintf("%s/%d", value, bits)
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", value, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
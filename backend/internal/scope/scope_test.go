@@ -0,0 +1,107 @@
+package scope
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubResolver resolves hostnames from a static map
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	var addrs []net.IPAddr
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestScope_Check(t *testing.T) {
+	resolver := stubResolver{
+		"www.example.com":      {"93.184.216.34"},
+		"metadata.example.com": {"169.254.169.254"},
+		"www.other.com":        {"203.0.113.10"},
+		"internal.example.com": {"10.0.0.5"},
+	}
+
+	open, err := NewScope(nil, nil, nil)
+	require.NoError(t, err)
+	open.Resolver = resolver
+
+	restricted, err := NewScope([]string{"10.0.0.0/8"}, []string{"example.com"}, []string{"10.0.0.5"})
+	require.NoError(t, err)
+	restricted.Resolver = resolver
+
+	tests := []struct {
+		name    string
+		scope   *Scope
+		target  string
+		wantErr bool
+	}{
+		{name: "Test Case 1: Public IP Without Allowlist", scope: open, target: "93.184.216.34"},
+		{name: "Test Case 2: Loopback Is Always Denied", scope: open, target: "127.0.0.1", wantErr: true},
+		{name: "Test Case 3: Metadata Address Is Always Denied", scope: open, target: "169.254.169.254", wantErr: true},
+		{name: "Test Case 4: Hostname Resolving Into Metadata Range", scope: open, target: "metadata.example.com", wantErr: true},
+		{name: "Test Case 5: Unresolvable Hostname", scope: open, target: "nx.example.com", wantErr: true},
+		{name: "Test Case 6: Allowed Domain", scope: restricted, target: "www.example.com"},
+		{name: "Test Case 7: Domain Outside Allowlist", scope: restricted, target: "www.other.com", wantErr: true},
+		{name: "Test Case 8: IP In Allowed Range", scope: restricted, target: "10.1.2.3"},
+		{name: "Test Case 9: IP Outside Allowed Range", scope: restricted, target: "93.184.216.34", wantErr: true},
+		{name: "Test Case 10: Allowed Domain Resolving Into Denied Range", scope: restricted, target: "internal.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.scope.Check(context.Background(), tt.target)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+
+			var scopeErr *Error
+			assert.ErrorAs(t, err, &scopeErr)
+		})
+	}
+}
+
+// rebindingResolver answers each lookup of a hostname with the next address of the list, like a short-TTL name would
+type rebindingResolver struct {
+	answers []string
+	lookups int
+}
+
+func (r *rebindingResolver) LookupIPAddr(_ context.Context, _ string) ([]net.IPAddr, error) {
+	answer := r.answers[r.lookups%len(r.answers)]
+	r.lookups++
+	return []net.IPAddr{{IP: net.ParseIP(answer)}}, nil
+}
+
+func TestScope_CheckReturnsCheckedAddresses(t *testing.T) {
+	scope, err := NewScope(nil, nil, nil)
+	require.NoError(t, err)
+	resolver := &rebindingResolver{answers: []string{"93.184.216.34", "127.0.0.1"}}
+	scope.Resolver = resolver
+
+	addresses, err := scope.Check(context.Background(), "rebind.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"93.184.216.34"}, addresses)
+	assert.Equal(t, 1, resolver.lookups, "the hostname should be resolved once")
+
+	// The next lookup points at loopback, which is what a second resolution before the scan would have seen
+	_, err = scope.Check(context.Background(), "rebind.example.com")
+	var scopeErr *Error
+	assert.ErrorAs(t, err, &scopeErr)
+
+	addresses, err = scope.Check(context.Background(), "10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.2.3"}, addresses)
+}
@@ -0,0 +1,141 @@
+package syslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// facilityLocal0 is the syslog facility the events are logged under
+	facilityLocal0 = 16
+	// severityNotice is used for port, host status and PTR changes
+	severityNotice = 5
+	// severityInfo is used for completed scans
+	severityInfo = 6
+	// nilValue is the RFC 5424 placeholder for empty header fields
+	nilValue = "-"
+)
+
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+var cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+var cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+
+// formatRFC5424 formats an event as an RFC 5424 syslog message
+func formatRFC5424(config Config, event Event) string {
+	severity := severityInfo
+	if event.Type != EventScanCompleted {
+		severity = severityNotice
+	}
+
+	params := [][2]string{
+		{"ip", event.IPAddress},
+		{"hostname", event.Hostname},
+	}
+	var message string
+	switch event.Type {
+	case EventPortChange:
+		params = append(params, [2]string{"port", strconv.Itoa(event.Port)}, [2]string{"change", event.Change})
+		message = fmt.Sprintf("port %d %s on %s", event.Port, event.Change, event.IPAddress)
+	case EventHostStatus:
+		params = append(params, [2]string{"change", event.Change})
+		message = fmt.Sprintf("%s on %s", event.Change, event.IPAddress)
+	case EventPTRChange:
+		params = append(params, [2]string{"name", event.Name}, [2]string{"change", event.Change})
+		message = fmt.Sprintf("%s %s on %s", event.Change, event.Name, event.IPAddress)
+	default:
+		params = append(params, [2]string{"open_ports", strconv.Itoa(event.OpenPorts)}, [2]string{"changes", strconv.Itoa(event.Changes)})
+		message = fmt.Sprintf("scan of %s completed with %d open ports and %d changes", event.IPAddress, event.OpenPorts, event.Changes)
+	}
+
+	if config.CEF {
+		message = formatCEF(event)
+	}
+
+	// A custom SD-ID must carry a private enterprise number, without one the structured data is left out
+	sd := nilValue
+	if config.EnterpriseNumber != "" {
+		var element strings.Builder
+		element.WriteString("[" + event.Type + "@" + config.EnterpriseNumber)
+		for _, param := range params {
+			if param[1] == "" {
+				continue
+			}
+			element.WriteString(" " + param[0] + `="` + sdValueEscaper.Replace(param[1]) + `"`)
+		}
+		element.WriteString("]")
+		sd = element.String()
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		facilityLocal0*8+severity,
+		event.Timestamp.UTC().Format(time.RFC3339),
+		headerField(config.Hostname),
+		headerField(config.AppName),
+		nilValue,
+		event.Type,
+		sd,
+		message,
+	)
+}
+
+// ValidEnterpriseNumber checks if a value is an IANA private enterprise number, optionally followed by sub-identifiers as in 32473.1
+func ValidEnterpriseNumber(value string) bool {
+	for _, part := range strings.Split(value, ".") {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// formatCEF formats an event as an ArcSight Common Event Format message
+func formatCEF(event Event) string {
+	signatureID, name, severity := "scan-completed", "Scan completed", "3"
+	switch event.Type {
+	case EventPortChange:
+		signatureID, name, severity = "port-"+event.Change, "Port "+event.Change, "5"
+	case EventHostStatus:
+		signatureID, name, severity = strings.ReplaceAll(event.Change, "_", "-"), "Host status changed", "5"
+	case EventPTRChange:
+		signatureID, name, severity = strings.ReplaceAll(event.Change, "_", "-"), "Reverse DNS changed", "5"
+	}
+
+	extensions := []string{
+		"rt=" + strconv.FormatInt(event.Timestamp.UnixMilli(), 10),
+		"dst=" + cefExtensionEscaper.Replace(event.IPAddress),
+	}
+	if event.Hostname != "" {
+		extensions = append(extensions, "dhost="+cefExtensionEscaper.Replace(event.Hostname))
+	}
+	switch event.Type {
+	case EventPortChange:
+		extensions = append(extensions, "dpt="+strconv.Itoa(event.Port), "act="+cefExtensionEscaper.Replace(event.Change))
+	case EventHostStatus:
+		extensions = append(extensions, "act="+cefExtensionEscaper.Replace(event.Change))
+	case EventPTRChange:
+		extensions = append(extensions, "cs1="+cefExtensionEscaper.Replace(event.Name), "cs1Label=ptr", "act="+cefExtensionEscaper.Replace(event.Change))
+	default:
+		extensions = append(extensions, "cn1="+strconv.Itoa(event.OpenPorts), "cn1Label=openPorts", "cn2="+strconv.Itoa(event.Changes), "cn2Label=changes")
+	}
+
+	return strings.Join([]string{
+		"CEF:0",
+		"nmap_project",
+		"port-scanner",
+		"1.0",
+		cefHeaderEscaper.Replace(signatureID),
+		cefHeaderEscaper.Replace(name),
+		severity,
+		strings.Join(extensions, " "),
+	}, "|")
+}
+
+// headerField returns the RFC 5424 nil value for empty header fields and strips spaces from the others
+func headerField(value string) string {
+	if value == "" {
+		return nilValue
+	}
+	return strings.ReplaceAll(value, " ", "_")
+}
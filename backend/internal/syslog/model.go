@@ -0,0 +1,37 @@
+package syslog
+
+import "time"
+
+const (
+	// EventPortChange is emitted for every port change found by a scan
+	EventPortChange = "port_change"
+	// EventScanCompleted is emitted once for every completed scan
+	EventScanCompleted = "scan_completed"
+	// EventHostStatus is emitted when a scan finds a host went down or came back
+	EventHostStatus = "host_status"
+	// EventPTRChange is emitted for every reverse DNS name a scan finds added to or removed from a host
+	EventPTRChange = "ptr_change"
+)
+
+// Config represents the syslog receiver and message format settings
+type Config struct {
+	Network          string // Transport to the receiver, udp or tcp
+	Address          string // Address of the receiver as host:port
+	AppName          string // APP-NAME field of the syslog header
+	Hostname         string // HOSTNAME field of the syslog header
+	CEF              bool   // Format the message body as CEF
+	EnterpriseNumber string // IANA private enterprise number of the structured data IDs, no structured data is sent when empty
+}
+
+// Event represents a single event sent to the SIEM
+type Event struct {
+	Type      string    // Event type (port_change, scan_completed, host_status, ptr_change)
+	Timestamp time.Time // Time of the event
+	IPAddress string    // IP address of the scanned host
+	Hostname  string    // Hostname of the scanned host
+	Port      int       // Port that changed, only set for port changes
+	Name      string    // Reverse DNS name that changed, only set for PTR changes
+	Change    string    // Change type (added, removed, host_down, host_up, ptr_added, ptr_removed), only set for port, host status and PTR changes
+	OpenPorts int       // Number of open ports, only set for completed scans
+	Changes   int       // Number of changed ports, only set for completed scans
+}
@@ -0,0 +1,127 @@
+package syslog
+
+import (
+	"backend/internal/scan"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SyslogClient represents a client for sending scan events to a syslog receiver
+type SyslogClient struct {
+	Logger *zap.Logger // Logger
+	Config Config      // Receiver and format settings
+
+	mu   sync.Mutex // Guards conn
+	conn net.Conn   // Connection to the receiver, opened lazily
+}
+
+// NewSyslogClient creates a new SyslogClient
+func NewSyslogClient(logger *zap.Logger, config Config) *SyslogClient {
+	return &SyslogClient{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// EmitScan sends one event per host status, reverse DNS and port change and one event for the completed scan
+func (s *SyslogClient) EmitScan(response *scan.ScanResponse) {
+	now := time.Now().UTC()
+
+	var events []Event
+	if response.HostChange != "" {
+		events = append(events, Event{
+			Type:      EventHostStatus,
+			Timestamp: now,
+			IPAddress: response.Host.IPAddress,
+			Hostname:  response.Host.Hostname,
+			Change:    response.HostChange,
+		})
+	}
+
+	for _, change := range response.NameChanges {
+		events = append(events, Event{
+			Type:      EventPTRChange,
+			Timestamp: now,
+			IPAddress: response.Host.IPAddress,
+			Hostname:  response.Host.Hostname,
+			Name:      change.Name,
+			Change:    change.Change,
+		})
+	}
+
+	for _, change := range response.PortChanges() {
+		events = append(events, Event{
+			Type:      EventPortChange,
+			Timestamp: now,
+			IPAddress: response.Host.IPAddress,
+			Hostname:  response.Host.Hostname,
+			Port:      change.Port,
+			Change:    change.Change,
+		})
+	}
+
+	events = append(events, Event{
+		Type:      EventScanCompleted,
+		Timestamp: now,
+		IPAddress: response.Host.IPAddress,
+		Hostname:  response.Host.Hostname,
+		OpenPorts: len(response.ScanResults),
+		Changes:   len(response.Changes),
+	})
+
+	for _, event := range events {
+		if err := s.send(formatRFC5424(s.Config, event)); err != nil {
+			s.Logger.Error("error sending syslog event", zap.String("type", event.Type), zap.Error(err))
+		}
+	}
+}
+
+// send writes a single message to the receiver, reconnecting once if the connection was lost
+func (s *SyslogClient) send(message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			s.conn, err = net.DialTimeout(s.Config.Network, s.Config.Address, 5*time.Second)
+			if err != nil {
+				return err
+			}
+		}
+
+		// TCP streams use octet counting framing (RFC 6587), UDP sends one message per datagram
+		frame := message
+		if s.Config.Network == "tcp" {
+			frame = fmt.Sprintf("%d %s", len(message), message)
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err = s.conn.Write([]byte(frame)); err == nil {
+			return nil
+		}
+
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return err
+}
+
+// Close closes the connection to the receiver
+func (s *SyslogClient) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,142 @@
+package syslog
+
+import (
+	"backend/internal/scan"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_formatRFC5424(t *testing.T) {
+	// 32473 is the enterprise number RFC 5612 reserves for documentation
+	config := Config{AppName: "nmap_project", Hostname: "scanner01", EnterpriseNumber: "32473"}
+	timestamp := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	change := Event{
+		Type:      EventPortChange,
+		Timestamp: timestamp,
+		IPAddress: "1.2.3.4",
+		Hostname:  "www.example.com",
+		Port:      443,
+		Change:    "added",
+	}
+	hostDown := Event{
+		Type:      EventHostStatus,
+		Timestamp: timestamp,
+		IPAddress: "1.2.3.4",
+		Change:    "host_down",
+	}
+	ptrAdded := Event{
+		Type:      EventPTRChange,
+		Timestamp: timestamp,
+		IPAddress: "1.2.3.4",
+		Name:      "host.example.net",
+		Change:    "ptr_added",
+	}
+	completed := Event{
+		Type:      EventScanCompleted,
+		Timestamp: timestamp,
+		IPAddress: "1.2.3.4",
+		OpenPorts: 2,
+		Changes:   1,
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		event  Event
+		want   string
+	}{
+		{
+			name:   "Test Case 1: Port Change",
+			config: config,
+			event:  change,
+			want:   `<133>1 2021-01-01T00:00:00Z scanner01 nmap_project - port_change [port_change@32473 ip="1.2.3.4" hostname="www.example.com" port="443" change="added"] port 443 added on 1.2.3.4`,
+		},
+		{
+			name:   "Test Case 2: Scan Completed",
+			config: config,
+			event:  completed,
+			want:   `<134>1 2021-01-01T00:00:00Z scanner01 nmap_project - scan_completed [scan_completed@32473 ip="1.2.3.4" open_ports="2" changes="1"] scan of 1.2.3.4 completed with 2 open ports and 1 changes`,
+		},
+		{
+			name:   "Test Case 3: Port Change As CEF",
+			config: Config{AppName: "nmap_project", CEF: true, EnterpriseNumber: "32473"},
+			event:  change,
+			want:   `<133>1 2021-01-01T00:00:00Z - nmap_project - port_change [port_change@32473 ip="1.2.3.4" hostname="www.example.com" port="443" change="added"] CEF:0|nmap_project|port-scanner|1.0|port-added|Port added|5|rt=1609459200000 dst=1.2.3.4 dhost=www.example.com dpt=443 act=added`,
+		},
+		{
+			name:   "Test Case 4: Host Down",
+			config: config,
+			event:  hostDown,
+			want:   `<133>1 2021-01-01T00:00:00Z scanner01 nmap_project - host_status [host_status@32473 ip="1.2.3.4" change="host_down"] host_down on 1.2.3.4`,
+		},
+		{
+			name:   "Test Case 5: Host Down As CEF",
+			config: Config{AppName: "nmap_project", CEF: true, EnterpriseNumber: "32473"},
+			event:  hostDown,
+			want:   `<133>1 2021-01-01T00:00:00Z - nmap_project - host_status [host_status@32473 ip="1.2.3.4" change="host_down"] CEF:0|nmap_project|port-scanner|1.0|host-down|Host status changed|5|rt=1609459200000 dst=1.2.3.4 act=host_down`,
+		},
+		{
+			name:   "Test Case 6: PTR Added",
+			config: config,
+			event:  ptrAdded,
+			want:   `<133>1 2021-01-01T00:00:00Z scanner01 nmap_project - ptr_change [ptr_change@32473 ip="1.2.3.4" name="host.example.net" change="ptr_added"] ptr_added host.example.net on 1.2.3.4`,
+		},
+		{
+			name:   "Test Case 7: PTR Added As CEF",
+			config: Config{AppName: "nmap_project", CEF: true, EnterpriseNumber: "32473"},
+			event:  ptrAdded,
+			want:   `<133>1 2021-01-01T00:00:00Z - nmap_project - ptr_change [ptr_change@32473 ip="1.2.3.4" name="host.example.net" change="ptr_added"] CEF:0|nmap_project|port-scanner|1.0|ptr-added|Reverse DNS changed|5|rt=1609459200000 dst=1.2.3.4 cs1=host.example.net cs1Label=ptr act=ptr_added`,
+		},
+		{
+			name:   "Test Case 8: No Enterprise Number",
+			config: Config{AppName: "nmap_project", Hostname: "scanner01"},
+			event:  change,
+			want:   `<133>1 2021-01-01T00:00:00Z scanner01 nmap_project - port_change - port 443 added on 1.2.3.4`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatRFC5424(tt.config, tt.event))
+		})
+	}
+}
+
+func TestSyslogClient_EmitScan(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := NewSyslogClient(zap.NewNop(), Config{Network: "udp", Address: conn.LocalAddr().String(), AppName: "nmap_project", EnterpriseNumber: "32473"})
+	defer client.Close()
+
+	client.EmitScan(&scan.ScanResponse{
+		Host:        scan.Host{IPAddress: "1.2.3.4"},
+		ScanResults: []*scan.ScanResult{{IPAddress: "1.2.3.4", Port: 80, Status: "open"}},
+		Changes:     map[int]string{80: "added"},
+	})
+
+	var messages []string
+	buffer := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(messages) < 2 {
+		n, _, err := conn.ReadFrom(buffer)
+		require.NoError(t, err)
+		messages = append(messages, string(buffer[:n]))
+	}
+
+	assert.Contains(t, messages[0], `port_change [port_change@32473 ip="1.2.3.4" port="80" change="added"]`)
+	assert.Contains(t, messages[1], `scan_completed [scan_completed@32473 ip="1.2.3.4" open_ports="1" changes="1"]`)
+}
+
+func TestValidEnterpriseNumber(t *testing.T) {
+	assert.True(t, ValidEnterpriseNumber("32473"))
+	assert.True(t, ValidEnterpriseNumber("32473.1.2"))
+	assert.False(t, ValidEnterpriseNumber("example"))
+	assert.False(t, ValidEnterpriseNumber("32473."))
+	assert.False(t, ValidEnterpriseNumber("-1"))
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout MySQL uses for timestamp columns
+const timeLayout = "2006-01-02 15:04:05"
+
+// ErrSubscriptionNotFound is returned when a subscription does not exist.
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// IDBClient is an interface that defines the methods for interacting with the webhook tables.
+type IDBClient interface {
+	InsertSubscription(ctx context.Context, subscription *Subscription) error
+	QuerySubscriptions(ctx context.Context, workspaceID string) ([]*Subscription, error)
+	DeleteSubscription(ctx context.Context, workspaceID string, subscriptionID string) error
+	InsertDelivery(ctx context.Context, delivery *Delivery) error
+	UpdateDelivery(ctx context.Context, delivery *Delivery) error
+	QueryDeliveries(ctx context.Context, workspaceID string, subscriptionID string) ([]*Delivery, error)
+}
+
+// DBClient is a struct that implements the IDBClient interface.
+type DBClient struct {
+	DB     *sql.DB
+	Logger *zap.Logger
+}
+
+// NewDBClient creates a new instance of DBClient sharing an existing database connection.
+func NewDBClient(conn *sql.DB, logger *zap.Logger) *DBClient {
+	return &DBClient{
+		DB:     conn,
+		Logger: logger,
+	}
+}
+
+// InsertSubscription inserts a subscription in the database and sets its ID and creation time.
+func (db *DBClient) InsertSubscription(ctx context.Context, subscription *Subscription) error {
+	subscription.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	// The filters are stored as JSON arrays so their values may contain commas
+	hosts, err := marshalList(subscription.Hosts)
+	if err != nil {
+		return err
+	}
+	ports, err := marshalList(subscription.Ports)
+	if err != nil {
+		return err
+	}
+	changeTypes, err := marshalList(subscription.ChangeTypes)
+	if err != nil {
+		return err
+	}
+
+	queryString := `INSERT INTO WebhookSubscriptions (workspace_id, url, secret, hosts, ports, change_types, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	res, err := db.DB.ExecContext(ctx, queryString, subscription.WorkspaceID, subscription.URL, subscription.Secret, hosts, ports, changeTypes, subscription.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	subscription.SubscriptionID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// QuerySubscriptions queries the database for all the webhook subscriptions of a workspace.
+func (db *DBClient) QuerySubscriptions(ctx context.Context, workspaceID string) ([]*Subscription, error) {
+	queryString := `SELECT subscription_id, workspace_id, url, secret, hosts, ports, change_types, created_at
+		FROM WebhookSubscriptions WHERE workspace_id = ? ORDER BY subscription_id`
+	rows, err := db.DB.QueryContext(ctx, queryString, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	subscriptions := []*Subscription{}
+	for rows.Next() {
+		var subscription Subscription
+		var hosts, ports, changeTypes, createdAtStr string
+		err := rows.Scan(&subscription.SubscriptionID, &subscription.WorkspaceID, &subscription.URL, &subscription.Secret, &hosts, &ports, &changeTypes, &createdAtStr)
+		if err != nil {
+			return nil, err
+		}
+
+		if subscription.Hosts, err = unmarshalList(hosts, func(value string) (string, error) { return value, nil }); err != nil {
+			return nil, err
+		}
+		if subscription.Ports, err = unmarshalList(ports, strconv.Atoi); err != nil {
+			return nil, err
+		}
+		if subscription.ChangeTypes, err = unmarshalList(changeTypes, func(value string) (string, error) { return value, nil }); err != nil {
+			return nil, err
+		}
+
+		if subscription.CreatedAt, err = time.Parse(timeLayout, createdAtStr); err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, &subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// DeleteSubscription deletes a subscription of a workspace and its delivery log from the database.
+func (db *DBClient) DeleteSubscription(ctx context.Context, workspaceID string, subscriptionID string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE d FROM WebhookDeliveries d JOIN WebhookSubscriptions s ON s.subscription_id = d.subscription_id
+		WHERE s.workspace_id = ? AND d.subscription_id = ?`, workspaceID, subscriptionID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM WebhookSubscriptions WHERE workspace_id = ? AND subscription_id = ?`, workspaceID, subscriptionID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return ErrSubscriptionNotFound
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
+
+// InsertDelivery inserts a delivery attempt in the delivery log and sets its ID.
+func (db *DBClient) InsertDelivery(ctx context.Context, delivery *Delivery) error {
+	queryString := `INSERT INTO WebhookDeliveries (subscription_id, attempt, status_code, error, success, timestamp) VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := db.DB.ExecContext(ctx, queryString, delivery.SubscriptionID, delivery.Attempt, delivery.StatusCode, delivery.Error, delivery.Success, delivery.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	delivery.DeliveryID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// UpdateDelivery records the outcome of a delivery attempt.
+func (db *DBClient) UpdateDelivery(ctx context.Context, delivery *Delivery) error {
+	_, err := db.DB.ExecContext(ctx, `UPDATE WebhookDeliveries SET status_code = ?, error = ?, success = ? WHERE delivery_id = ?`,
+		delivery.StatusCode, delivery.Error, delivery.Success, delivery.DeliveryID)
+	return err
+}
+
+// QueryDeliveries queries the delivery log of a subscription of a workspace, newest first.
+func (db *DBClient) QueryDeliveries(ctx context.Context, workspaceID string, subscriptionID string) ([]*Delivery, error) {
+	queryString := `SELECT d.delivery_id, d.subscription_id, d.attempt, d.status_code, d.error, d.success, d.timestamp
+		FROM WebhookDeliveries d JOIN WebhookSubscriptions s ON s.subscription_id = d.subscription_id
+		WHERE s.workspace_id = ? AND d.subscription_id = ? ORDER BY d.delivery_id DESC`
+	rows, err := db.DB.QueryContext(ctx, queryString, workspaceID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := []*Delivery{}
+	for rows.Next() {
+		var delivery Delivery
+		var timestampStr string
+		err := rows.Scan(&delivery.DeliveryID, &delivery.SubscriptionID, &delivery.Attempt, &delivery.StatusCode, &delivery.Error, &delivery.Success, &timestampStr)
+		if err != nil {
+			return nil, err
+		}
+
+		if delivery.Timestamp, err = time.Parse(timeLayout, timestampStr); err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// marshalList encodes a list as a JSON array
+func marshalList[T any](values []T) (string, error) {
+	if len(values) == 0 {
+		return "[]", nil
+	}
+	encoded, err := json.Marshal(values)
+	return string(encoded), err
+}
+
+// unmarshalList decodes a list stored as a JSON array. Subscriptions created before the lists were stored as JSON
+// hold comma separated values, which are parsed one by one.
+func unmarshalList[T any](value string, parse func(string) (T, error)) ([]T, error) {
+	if strings.HasPrefix(value, "[") {
+		var values []T
+		if err := json.Unmarshal([]byte(value), &values); err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values, nil
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+
+	var values []T
+	for _, item := range strings.Split(value, ",") {
+		parsed, err := parse(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, parsed)
+	}
+	return values, nil
+}
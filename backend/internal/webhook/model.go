@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"backend/internal/scan"
+	"time"
+)
+
+// Subscription represents a webhook subscribed to port changes.
+// Empty filters match everything.
+type Subscription struct {
+	SubscriptionID string    `db:"subscription_id" json:"subscription_id,omitempty"`
+	WorkspaceID    string    `db:"workspace_id" json:"workspace_id"`
+	URL            string    `db:"url" json:"url" validate:"required,http_url,max=2048"`
+	Secret         string    `db:"secret" json:"secret,omitempty" validate:"required,min=16,max=255"` // Shared secret used to sign payloads
+	Hosts          []string  `db:"hosts" json:"hosts,omitempty" validate:"dive,required"`             // Only notify for these IP addresses or hostnames
+	Ports          []int     `db:"ports" json:"ports,omitempty" validate:"dive,min=0,max=65535"`      // Only notify for these ports
+	ChangeTypes    []string  `db:"change_types" json:"change_types,omitempty" validate:"dive,oneof=added removed host_down host_up ptr_added ptr_removed"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+// Event represents the JSON payload posted to a webhook after a scan with changes
+type Event struct {
+	Event       string            `json:"event"`                  // Event type
+	Host        scan.Host         `json:"host"`                   // Host that was scanned
+	Timestamp   time.Time         `json:"timestamp"`              // Time the event was created
+	Changes     []scan.PortChange `json:"changes,omitempty"`      // Port changes matching the subscription
+	HostStatus  string            `json:"host_status,omitempty"`  // Status of the host, only set for host status changes
+	HostChange  string            `json:"host_change,omitempty"`  // Change of the host status (host_down, host_up), only set for host status changes
+	NameChanges []scan.NameChange `json:"name_changes,omitempty"` // Reverse DNS changes matching the subscription, only set for hostname changes
+}
+
+// Delivery represents a single attempt to deliver an event to a webhook
+type Delivery struct {
+	DeliveryID     string    `db:"delivery_id" json:"delivery_id,omitempty"`
+	SubscriptionID string    `db:"subscription_id" json:"subscription_id"`
+	Attempt        int       `db:"attempt" json:"attempt"`
+	StatusCode     int       `db:"status_code" json:"status_code,omitempty"`
+	Error          string    `db:"error" json:"error,omitempty"`
+	Success        bool      `db:"success" json:"success"`
+	Timestamp      time.Time `db:"timestamp" json:"timestamp"`
+}
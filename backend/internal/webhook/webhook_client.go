@@ -0,0 +1,318 @@
+package webhook
+
+import (
+	"backend/internal/scan"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracer creates the spans of the webhook package
+var tracer = otel.Tracer("backend/internal/webhook")
+
+const (
+	// EventPortsChanged is the event type posted when a scan finds port changes
+	EventPortsChanged = "ports.changed"
+	// EventHostStatusChanged is the event type posted when a scan finds a host went down or came back
+	EventHostStatusChanged = "host.status_changed"
+	// EventHostnamesChanged is the event type posted when a scan finds the reverse DNS names of a host changed
+	EventHostnamesChanged = "hostnames.changed"
+	// SignatureHeader is the header carrying the HMAC-SHA256 signature of the payload
+	SignatureHeader = "X-Signature-256"
+	// DefaultMaxAttempts is the number of times a delivery is attempted before giving up
+	DefaultMaxAttempts = 5
+	// DefaultBackoff is the delay before the first retry, doubled after every attempt
+	DefaultBackoff = time.Second
+)
+
+// ErrForbiddenDestination is returned when a webhook URL points at an address deliveries may not be sent to
+var ErrForbiddenDestination = errors.New("webhook destination is a loopback, link-local or private address")
+
+// WebhookClient represents a client for delivering change events to webhook subscriptions
+type WebhookClient struct {
+	Logger          *zap.Logger   // Logger
+	DBClient        IDBClient     // Database client
+	HTTPClient      *http.Client  // HTTP client used to post events
+	MaxAttempts     int           // Maximum number of delivery attempts
+	Backoff         time.Duration // Delay before the first retry
+	AllowedNetworks []*net.IPNet  // Private networks webhooks may nevertheless be delivered to
+}
+
+// NewWebhookClient creates a new WebhookClient. Its HTTP client refuses to connect to the destinations
+// DestinationAllowed rejects, checked on the address actually dialed so neither DNS nor redirects get around it.
+func NewWebhookClient(logger *zap.Logger, DBClient IDBClient) *WebhookClient {
+	w := &WebhookClient{
+		Logger:      logger,
+		DBClient:    DBClient,
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+	}
+
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !w.DestinationAllowed(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenDestination, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect to the destination on our behalf, out of reach of the dial check
+	transport.Proxy = nil
+	w.HTTPClient = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+
+	return w
+}
+
+// DestinationAllowed checks if events may be posted to an address. Loopback, link-local, which covers the cloud
+// metadata address, unspecified and multicast addresses are always refused, private ones unless in AllowedNetworks.
+func (w *WebhookClient) DestinationAllowed(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+	if !ip.IsPrivate() {
+		return true
+	}
+
+	for _, network := range w.AllowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyChanges posts a change event to every subscription matching the changes of a scan response.
+// Deliveries run in the background so the scan request isn't held up by slow receivers.
+func (w *WebhookClient) NotifyChanges(ctx context.Context, response *scan.ScanResponse) {
+	if len(response.Changes) == 0 && response.HostChange == "" && len(response.NameChanges) == 0 {
+		return
+	}
+
+	subscriptions, err := w.DBClient.QuerySubscriptions(ctx, response.WorkspaceID)
+	if err != nil {
+		w.Logger.Error("error querying webhook subscriptions", zap.Error(err))
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, subscription := range subscriptions {
+		if matchHostChange(subscription, response.Host, response.HostChange) {
+			event := Event{
+				Event:      EventHostStatusChanged,
+				Host:       response.Host,
+				Timestamp:  now,
+				HostStatus: response.HostStatus,
+				HostChange: response.HostChange,
+			}
+			go w.deliver(context.WithoutCancel(ctx), subscription, event)
+		}
+
+		if nameChanges := matchNameChanges(subscription, response.Host, response.NameChanges); len(nameChanges) > 0 {
+			event := Event{
+				Event:       EventHostnamesChanged,
+				Host:        response.Host,
+				Timestamp:   now,
+				NameChanges: nameChanges,
+			}
+			go w.deliver(context.WithoutCancel(ctx), subscription, event)
+		}
+
+		changes := matchChanges(subscription, response.Host, response.PortChanges())
+		if len(changes) == 0 {
+			continue
+		}
+
+		event := Event{
+			Event:     EventPortsChanged,
+			Host:      response.Host,
+			Timestamp: now,
+			Changes:   changes,
+		}
+
+		// Deliveries outlive the request but stay part of its trace
+		go w.deliver(context.WithoutCancel(ctx), subscription, event)
+	}
+}
+
+// deliver sends an event to a subscription and records every attempt in the delivery log
+func (w *WebhookClient) deliver(ctx context.Context, subscription *Subscription, event Event) {
+	ctx, span := tracer.Start(ctx, "WebhookClient.deliver", trace.WithAttributes(attribute.String("webhook.subscription_id", subscription.SubscriptionID)))
+	defer span.End()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		w.Logger.Error("error marshaling webhook event", zap.Error(err))
+		return
+	}
+
+	w.send(ctx, subscription, body)
+}
+
+// send posts a signed payload to a subscription, retrying with exponential backoff until it succeeds or runs out of attempts.
+// Every attempt is logged before it is made and updated with its outcome, so a shutdown during a retry still leaves a record.
+func (w *WebhookClient) send(ctx context.Context, subscription *Subscription, body []byte) []*Delivery {
+	var deliveries []*Delivery
+	backoff := w.Backoff
+	for attempt := 1; attempt <= w.MaxAttempts; attempt++ {
+		delivery := &Delivery{
+			SubscriptionID: subscription.SubscriptionID,
+			Attempt:        attempt,
+			Timestamp:      time.Now().UTC().Truncate(time.Second),
+		}
+		deliveries = append(deliveries, delivery)
+		if err := w.DBClient.InsertDelivery(ctx, delivery); err != nil {
+			w.Logger.Error("error inserting webhook delivery", zap.Error(err))
+		}
+
+		statusCode, err := w.post(ctx, subscription, body)
+		delivery.StatusCode = statusCode
+		delivery.Success = err == nil
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if delivery.DeliveryID != "" {
+			if err := w.DBClient.UpdateDelivery(ctx, delivery); err != nil {
+				w.Logger.Error("error updating webhook delivery", zap.Error(err))
+			}
+		}
+		if err == nil {
+			return deliveries
+		}
+
+		w.Logger.Warn("webhook delivery failed", zap.String("url", subscription.URL), zap.Int("attempt", attempt), zap.Error(err))
+
+		if attempt < w.MaxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return deliveries
+}
+
+// post makes a single signed request to a subscription, any non 2xx status is an error
+func (w *WebhookClient) post(ctx context.Context, subscription *Subscription, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "sha256="+Sign(subscription.Secret, body))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := w.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of a payload using the shared secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// matchChanges returns the changes that match the filters of a subscription
+func matchChanges(subscription *Subscription, host scan.Host, changes []scan.PortChange) []scan.PortChange {
+	if len(subscription.Hosts) > 0 && !contains(subscription.Hosts, host.IPAddress) && !contains(subscription.Hosts, host.Hostname) {
+		return nil
+	}
+
+	var matched []scan.PortChange
+	for _, change := range changes {
+		if len(subscription.Ports) > 0 && !containsPort(subscription.Ports, change.Port) {
+			continue
+		}
+		if len(subscription.ChangeTypes) > 0 && !contains(subscription.ChangeTypes, change.Change) {
+			continue
+		}
+		matched = append(matched, change)
+	}
+
+	return matched
+}
+
+// matchHostChange checks if a host status change matches the host and change type filters of a subscription.
+// Port filters don't apply, as every port of the host is affected.
+func matchHostChange(subscription *Subscription, host scan.Host, change string) bool {
+	if change == "" {
+		return false
+	}
+	if len(subscription.Hosts) > 0 && !contains(subscription.Hosts, host.IPAddress) && !contains(subscription.Hosts, host.Hostname) {
+		return false
+	}
+	return len(subscription.ChangeTypes) == 0 || contains(subscription.ChangeTypes, change)
+}
+
+// matchNameChanges returns the reverse DNS changes that match the host and change type filters of a subscription.
+// Port filters don't apply, as names belong to the host.
+func matchNameChanges(subscription *Subscription, host scan.Host, changes []scan.NameChange) []scan.NameChange {
+	if len(subscription.Hosts) > 0 && !contains(subscription.Hosts, host.IPAddress) && !contains(subscription.Hosts, host.Hostname) {
+		return nil
+	}
+
+	var matched []scan.NameChange
+	for _, change := range changes {
+		if len(subscription.ChangeTypes) > 0 && !contains(subscription.ChangeTypes, change.Change) {
+			continue
+		}
+		matched = append(matched, change)
+	}
+
+	return matched
+}
+
+// contains checks if a non empty value is in a list
+func contains(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPort checks if a port is in a list
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
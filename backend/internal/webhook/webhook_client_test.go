@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"backend/internal/scan"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_matchChanges(t *testing.T) {
+	host := scan.Host{IPAddress: "1.2.3.4", Hostname: "www.example.com"}
+	changes := []scan.PortChange{
+		{Port: 80, Change: "added"},
+		{Port: 443, Change: "removed"},
+	}
+
+	tests := []struct {
+		name         string
+		subscription *Subscription
+		want         []scan.PortChange
+	}{
+		{
+			name:         "Test Case 1: No Filters",
+			subscription: &Subscription{},
+			want:         changes,
+		},
+		{
+			name:         "Test Case 2: Matching Hostname",
+			subscription: &Subscription{Hosts: []string{"www.example.com"}},
+			want:         changes,
+		},
+		{
+			name:         "Test Case 3: Other Host",
+			subscription: &Subscription{Hosts: []string{"5.6.7.8"}},
+			want:         nil,
+		},
+		{
+			name:         "Test Case 4: Port Filter",
+			subscription: &Subscription{Ports: []int{443}},
+			want:         []scan.PortChange{{Port: 443, Change: "removed"}},
+		},
+		{
+			name:         "Test Case 5: Change Type Filter",
+			subscription: &Subscription{ChangeTypes: []string{"added"}},
+			want:         []scan.PortChange{{Port: 80, Change: "added"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, matchChanges(tt.subscription, host, changes), "matchChanges(%v, %v, %v)", tt.subscription, host, changes)
+		})
+	}
+}
+
+func Test_matchHostChange(t *testing.T) {
+	host := scan.Host{IPAddress: "1.2.3.4", Hostname: "www.example.com"}
+
+	assert.True(t, matchHostChange(&Subscription{}, host, scan.HostChangeDown))
+	assert.True(t, matchHostChange(&Subscription{Ports: []int{443}}, host, scan.HostChangeDown))
+	assert.True(t, matchHostChange(&Subscription{ChangeTypes: []string{"host_up"}}, host, scan.HostChangeUp))
+	assert.False(t, matchHostChange(&Subscription{ChangeTypes: []string{"added"}}, host, scan.HostChangeDown))
+	assert.False(t, matchHostChange(&Subscription{Hosts: []string{"5.6.7.8"}}, host, scan.HostChangeDown))
+	assert.False(t, matchHostChange(&Subscription{}, host, ""))
+}
+
+func Test_matchNameChanges(t *testing.T) {
+	host := scan.Host{IPAddress: "1.2.3.4", Hostname: "www.example.com"}
+	changes := []scan.NameChange{
+		{Name: "new.example.net", Change: scan.NameChangePTRAdded},
+		{Name: "old.example.net", Change: scan.NameChangePTRRemoved},
+	}
+
+	assert.Equal(t, changes, matchNameChanges(&Subscription{}, host, changes))
+	assert.Equal(t, changes, matchNameChanges(&Subscription{Ports: []int{443}}, host, changes))
+	assert.Equal(t, changes[1:], matchNameChanges(&Subscription{ChangeTypes: []string{"ptr_removed"}}, host, changes))
+	assert.Empty(t, matchNameChanges(&Subscription{ChangeTypes: []string{"added"}}, host, changes))
+	assert.Empty(t, matchNameChanges(&Subscription{Hosts: []string{"5.6.7.8"}}, host, changes))
+}
+
+// deliveryLog keeps the delivery log in memory, the other methods aren't used when sending
+type deliveryLog struct {
+	IDBClient
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+func (l *deliveryLog) InsertDelivery(_ context.Context, delivery *Delivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delivery.DeliveryID = strconv.Itoa(len(l.deliveries) + 1)
+	l.deliveries = append(l.deliveries, *delivery)
+	return nil
+}
+
+func (l *deliveryLog) UpdateDelivery(_ context.Context, delivery *Delivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id, _ := strconv.Atoi(delivery.DeliveryID)
+	l.deliveries[id-1] = *delivery
+	return nil
+}
+
+// last returns the newest entry of the delivery log
+func (l *deliveryLog) last() Delivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.deliveries[len(l.deliveries)-1]
+}
+
+func TestWebhookClient_send(t *testing.T) {
+	body := []byte(`{"event":"ports.changed"}`)
+	secret := "0123456789abcdef"
+	log := &deliveryLog{}
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := calls.Add(1)
+		received, _ := io.ReadAll(r.Body)
+		assert.Equal(t, body, received)
+		assert.Equal(t, "sha256="+Sign(secret, body), r.Header.Get(SignatureHeader))
+
+		// The attempt is logged before the request is made
+		attempt := log.last()
+		assert.Equal(t, int(call), attempt.Attempt)
+		assert.Zero(t, attempt.StatusCode)
+		assert.False(t, attempt.Success)
+
+		// Fail the first attempt to exercise the retry
+		if call == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewWebhookClient(zap.NewNop(), log)
+	client.Backoff = 0
+	// The test server listens on loopback, which the default client refuses
+	client.HTTPClient = server.Client()
+
+	deliveries := client.send(context.Background(), &Subscription{SubscriptionID: "1", URL: server.URL, Secret: secret}, body)
+
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Len(t, deliveries, 2)
+	assert.False(t, deliveries[0].Success)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].StatusCode)
+	assert.True(t, deliveries[1].Success)
+	assert.Equal(t, 2, deliveries[1].Attempt)
+
+	// Every attempt is updated with its outcome
+	require.Len(t, log.deliveries, 2)
+	assert.Equal(t, http.StatusInternalServerError, log.deliveries[0].StatusCode)
+	assert.NotEmpty(t, log.deliveries[0].Error)
+	assert.True(t, log.deliveries[1].Success)
+	assert.Equal(t, http.StatusNoContent, log.deliveries[1].StatusCode)
+}
+
+func TestWebhookClient_sendRefusesLoopback(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewWebhookClient(zap.NewNop(), &deliveryLog{})
+	client.MaxAttempts = 2
+	client.Backoff = 0
+
+	// localhost only resolves to loopback, which is checked once resolved
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	deliveries := client.send(context.Background(), &Subscription{SubscriptionID: "1", URL: url, Secret: "0123456789abcdef"}, []byte(`{}`))
+
+	assert.Equal(t, int32(0), calls.Load())
+	require.Len(t, deliveries, 2)
+	assert.False(t, deliveries[1].Success)
+	assert.Contains(t, deliveries[1].Error, ErrForbiddenDestination.Error())
+}
+
+func TestWebhookClient_DestinationAllowed(t *testing.T) {
+	_, internal, err := net.ParseCIDR("10.20.0.0/16")
+	require.NoError(t, err)
+	client := NewWebhookClient(zap.NewNop(), nil)
+	client.AllowedNetworks = []*net.IPNet{internal}
+
+	for address, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:2800::1":    true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"0.0.0.0":         false,
+		"10.0.0.1":        false,
+		"192.168.1.1":     false,
+		"172.16.0.1":      false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+		"10.20.1.1":       true,
+	} {
+		assert.Equalf(t, want, client.DestinationAllowed(net.ParseIP(address)), "DestinationAllowed(%s)", address)
+	}
+}
+
+func Test_unmarshalList(t *testing.T) {
+	hosts, err := marshalList([]string{"10.0.0.1", "web,01.example.com"})
+	require.NoError(t, err)
+	got, err := unmarshalList(hosts, func(value string) (string, error) { return value, nil })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "web,01.example.com"}, got, "values containing commas should survive a round trip")
+
+	empty, err := marshalList([]int(nil))
+	require.NoError(t, err)
+	ports, err := unmarshalList(empty, strconv.Atoi)
+	require.NoError(t, err)
+	assert.Nil(t, ports)
+
+	// Subscriptions stored before the lists were JSON are still read
+	ports, err = unmarshalList("22,443", strconv.Atoi)
+	require.NoError(t, err)
+	assert.Equal(t, []int{22, 443}, ports)
+
+	_, err = unmarshalList("22,https", strconv.Atoi)
+	assert.Error(t, err)
+}
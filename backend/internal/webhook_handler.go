@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/webhook"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) postWebhookHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var subscription webhook.Subscription
+	if err := c.ShouldBindJSON(&subscription); err != nil {
+		s.Logger.Error("unable to bind json", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+
+	if err := validate.Struct(subscription); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	// Hostnames are checked on every delivery, a URL with a refused address is rejected right away
+	if destination, err := url.Parse(subscription.URL); err == nil {
+		if ip := net.ParseIP(destination.Hostname()); ip != nil && !s.WebhookClient.DestinationAllowed(ip) {
+			abortWithError(c, apierror.Invalid("url", "destination", "Webhooks can't be delivered to loopback, link-local or private addresses"))
+			return
+		}
+	}
+
+	subscription.WorkspaceID = getWorkspaceID(c)
+	if err := s.WebhookClient.DBClient.InsertSubscription(ctx, &subscription); err != nil {
+		s.Logger.Error("unable to insert webhook subscription", zap.Error(err))
+		abortWithError(c, storeError("Unable to create webhook subscription", err))
+		return
+	}
+
+	// Never echo the shared secret back
+	subscription.Secret = ""
+
+	setAuditTarget(c, subscription.SubscriptionID)
+	c.JSON(http.StatusCreated, subscription)
+}
+
+func (s *Server) getWebhooksHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	subscriptions, err := s.WebhookClient.DBClient.QuerySubscriptions(ctx, getWorkspaceID(c))
+	if err != nil {
+		s.Logger.Error("unable to query webhook subscriptions", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query webhook subscriptions", err))
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		subscription.Secret = ""
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func (s *Server) deleteWebhookHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.WebhookClient.DBClient.DeleteSubscription(ctx, getWorkspaceID(c), c.Param("id"))
+	if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to delete webhook subscription", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to delete webhook subscription", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) getWebhookDeliveriesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	deliveries, err := s.WebhookClient.DBClient.QueryDeliveries(ctx, getWorkspaceID(c), c.Param("id"))
+	if err != nil {
+		s.Logger.Error("unable to query webhook deliveries", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query webhook deliveries", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,197 @@
+package workspace
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout MySQL uses for timestamp columns
+const timeLayout = "2006-01-02 15:04:05"
+
+// ErrWorkspaceNotFound is returned when a workspace does not exist.
+var ErrWorkspaceNotFound = errors.New("workspace not found")
+
+// ErrMemberNotFound is returned when a principal isn't a member of a workspace.
+var ErrMemberNotFound = errors.New("workspace member not found")
+
+// IDBClient is an interface that defines the methods for interacting with the workspace tables.
+type IDBClient interface {
+	InsertWorkspace(ctx context.Context, workspace *Workspace) error
+	QueryWorkspaces(ctx context.Context) ([]*Workspace, error)
+	QueryWorkspacesForPrincipal(ctx context.Context, principalID string) ([]*Workspace, error)
+	InsertMember(ctx context.Context, member Member) error
+	QueryMembers(ctx context.Context, workspaceID string) ([]*Member, error)
+	QueryMember(ctx context.Context, workspaceID, principalID string) (*Member, error)
+	DeleteMember(ctx context.Context, member Member) error
+}
+
+// DBClient is a struct that implements the IDBClient interface.
+type DBClient struct {
+	DB     *sql.DB
+	Logger *zap.Logger
+}
+
+// NewDBClient creates a new instance of DBClient sharing an existing database connection.
+func NewDBClient(conn *sql.DB, logger *zap.Logger) *DBClient {
+	return &DBClient{
+		DB:     conn,
+		Logger: logger,
+	}
+}
+
+// InsertWorkspace inserts a workspace in the database and sets its ID and creation time.
+func (db *DBClient) InsertWorkspace(ctx context.Context, workspace *Workspace) error {
+	workspace.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := db.DB.ExecContext(ctx, `INSERT INTO Workspaces (name, created_at) VALUES (?, ?)`, workspace.Name, workspace.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	workspace.WorkspaceID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// QueryWorkspaces queries the database for all the workspaces.
+func (db *DBClient) QueryWorkspaces(ctx context.Context) ([]*Workspace, error) {
+	return db.queryWorkspaces(ctx, `SELECT workspace_id, name, created_at FROM Workspaces ORDER BY workspace_id`)
+}
+
+// QueryWorkspace queries the database for a workspace, ErrWorkspaceNotFound is returned when it doesn't exist.
+func (db *DBClient) QueryWorkspace(ctx context.Context, workspaceID string) (*Workspace, error) {
+	workspaces, err := db.queryWorkspaces(ctx, `SELECT workspace_id, name, created_at FROM Workspaces WHERE workspace_id = ?`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(workspaces) == 0 {
+		return nil, ErrWorkspaceNotFound
+	}
+	return workspaces[0], nil
+}
+
+// QueryWorkspacesForPrincipal queries the database for the workspaces a principal is a member of.
+func (db *DBClient) QueryWorkspacesForPrincipal(ctx context.Context, principalID string) ([]*Workspace, error) {
+	queryString := `SELECT w.workspace_id, w.name, w.created_at FROM Workspaces w
+		JOIN WorkspaceMembers m ON m.workspace_id = w.workspace_id WHERE m.principal_id = ? ORDER BY w.workspace_id`
+	return db.queryWorkspaces(ctx, queryString, principalID)
+}
+
+// InsertMember adds a principal to a workspace, or changes its role when it already is a member.
+func (db *DBClient) InsertMember(ctx context.Context, member Member) error {
+	res, err := db.DB.ExecContext(ctx, `INSERT INTO WorkspaceMembers (workspace_id, principal_id, role)
+		SELECT workspace_id, ?, ? FROM Workspaces WHERE workspace_id = ?
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`, member.PrincipalID, member.Role, member.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// Nothing was written either because the workspace doesn't exist or the principal already is a member with that role
+	if affected == 0 {
+		if _, err := db.QueryWorkspace(ctx, member.WorkspaceID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueryMembers queries the database for the members of a workspace.
+func (db *DBClient) QueryMembers(ctx context.Context, workspaceID string) ([]*Member, error) {
+	return db.queryMembers(ctx, `SELECT workspace_id, principal_id, role FROM WorkspaceMembers WHERE workspace_id = ? ORDER BY principal_id`, workspaceID)
+}
+
+// QueryMember queries the database for the membership of a principal, ErrMemberNotFound is returned when it isn't a member.
+func (db *DBClient) QueryMember(ctx context.Context, workspaceID, principalID string) (*Member, error) {
+	members, err := db.queryMembers(ctx, `SELECT workspace_id, principal_id, role FROM WorkspaceMembers WHERE workspace_id = ? AND principal_id = ?`, workspaceID, principalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(members) == 0 {
+		return nil, ErrMemberNotFound
+	}
+	return members[0], nil
+}
+
+// DeleteMember removes a principal from a workspace.
+func (db *DBClient) DeleteMember(ctx context.Context, member Member) error {
+	res, err := db.DB.ExecContext(ctx, `DELETE FROM WorkspaceMembers WHERE workspace_id = ? AND principal_id = ?`, member.WorkspaceID, member.PrincipalID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+// queryWorkspaces runs a query returning workspaces
+func (db *DBClient) queryWorkspaces(ctx context.Context, queryString string, args ...any) ([]*Workspace, error) {
+	rows, err := db.DB.QueryContext(ctx, queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	workspaces := []*Workspace{}
+	for rows.Next() {
+		var workspace Workspace
+		var createdAtStr string
+		if err := rows.Scan(&workspace.WorkspaceID, &workspace.Name, &createdAtStr); err != nil {
+			return nil, err
+		}
+
+		if workspace.CreatedAt, err = time.Parse(timeLayout, createdAtStr); err != nil {
+			return nil, err
+		}
+
+		workspaces = append(workspaces, &workspace)
+	}
+
+	return workspaces, rows.Err()
+}
+
+// queryMembers runs a query returning workspace members
+func (db *DBClient) queryMembers(ctx context.Context, queryString string, args ...any) ([]*Member, error) {
+	rows, err := db.DB.QueryContext(ctx, queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	members := []*Member{}
+	for rows.Next() {
+		var member Member
+		if err := rows.Scan(&member.WorkspaceID, &member.PrincipalID, &member.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+
+	return members, rows.Err()
+}
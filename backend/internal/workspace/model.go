@@ -0,0 +1,27 @@
+package workspace
+
+import "time"
+
+// DefaultWorkspaceID is the workspace that owns the data created before workspaces existed
+const DefaultWorkspaceID = "1"
+
+const (
+	// RoleMember allows scanning and reading the history of a workspace, as far as the scopes of the caller allow
+	RoleMember = "member"
+	// RoleAdmin additionally allows managing the policies, tags, schedules and notifications of a workspace
+	RoleAdmin = "admin"
+)
+
+// Workspace represents a tenant owning hosts, scan runs, schedules, policies and notifications
+type Workspace struct {
+	WorkspaceID string    `db:"workspace_id" json:"workspace_id"`
+	Name        string    `db:"name" json:"name" validate:"required,max=255"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// Member represents an API caller belonging to a workspace
+type Member struct {
+	WorkspaceID string `db:"workspace_id" json:"workspace_id"`
+	PrincipalID string `db:"principal_id" json:"principal_id" validate:"required,max=255"` // ID of an API key (key:<id>) or token subject (jwt:<sub>)
+	Role        string `db:"role" json:"role" validate:"oneof=member admin"`               // Role of the caller in the workspace (member, admin)
+}
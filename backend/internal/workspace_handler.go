@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/workspace"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) postWorkspaceHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var ws workspace.Workspace
+	if err := c.ShouldBindJSON(&ws); err != nil {
+		s.Logger.Error("unable to bind json", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+
+	if err := validate.Struct(ws); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	if err := s.WorkspaceDBClient.InsertWorkspace(ctx, &ws); err != nil {
+		s.Logger.Error("unable to insert workspace", zap.Error(err))
+		abortWithError(c, storeError("Unable to create workspace", err))
+		return
+	}
+
+	setAuditTarget(c, ws.WorkspaceID)
+	c.JSON(http.StatusCreated, ws)
+}
+
+// getWorkspacesHandler lists every workspace to admins and the workspaces of the caller to everyone else
+func (s *Server) getWorkspacesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var workspaces []*workspace.Workspace
+	var err error
+	if principal := getPrincipal(c); principal.HasScope(auth.ScopeAdmin) {
+		workspaces, err = s.WorkspaceDBClient.QueryWorkspaces(ctx)
+	} else {
+		workspaces, err = s.WorkspaceDBClient.QueryWorkspacesForPrincipal(ctx, principal.ID)
+	}
+	if err != nil {
+		s.Logger.Error("unable to query workspaces", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query workspaces", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+func (s *Server) postWorkspaceMemberHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var member workspace.Member
+	if err := c.ShouldBindJSON(&member); err != nil {
+		s.Logger.Error("unable to bind json", zap.Error(err))
+		abortWithError(c, apierror.Binding(err))
+		return
+	}
+	member.WorkspaceID = c.Param("id")
+	if member.Role == "" {
+		member.Role = workspace.RoleMember
+	}
+	setAuditTarget(c, member.PrincipalID)
+
+	if err := validate.Struct(member); err != nil {
+		s.Logger.Error("validation error", zap.Error(err))
+		abortWithError(c, apierror.Validation(err))
+		return
+	}
+
+	err := s.WorkspaceDBClient.InsertMember(ctx, member)
+	if errors.Is(err, workspace.ErrWorkspaceNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to insert workspace member", zap.Error(err))
+		abortWithError(c, storeError("Unable to add workspace member", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func (s *Server) getWorkspaceMembersHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	members, err := s.WorkspaceDBClient.QueryMembers(ctx, c.Param("id"))
+	if err != nil {
+		s.Logger.Error("unable to query workspace members", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to query workspace members", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (s *Server) deleteWorkspaceMemberHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	setAuditTarget(c, c.Param("principal"))
+	err := s.WorkspaceDBClient.DeleteMember(ctx, workspace.Member{WorkspaceID: c.Param("id"), PrincipalID: c.Param("principal")})
+	if errors.Is(err, workspace.ErrMemberNotFound) {
+		abortWithError(c, apierror.Wrap(apierror.CodeNotFound, err))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("unable to delete workspace member", zap.Error(err))
+		abortWithError(c, apierror.Internal("Unable to remove workspace member", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,3 +1,5 @@
+import { env } from '$env/dynamic/private';
+
 /** @type {import('./$types').PageServerLoad} */
 export async function load() {
     return {};
@@ -12,6 +14,7 @@ export const actions = {
         const options = {
             headers: {
                 'Content-Type': 'application/json',
+                'X-API-Key': env.API_KEY ?? '',
             },
             method: 'POST',
             body: JSON.stringify(body),
@@ -40,4 +43,28 @@ export const actions = {
             changes,
         };
     },
+    import: async ({ request }) => {
+        const formData = await request.formData();
+        const options = {
+            headers: {
+                'X-API-Key': env.API_KEY ?? '',
+            },
+            method: 'POST',
+            body: formData,
+        };
+        const url = `http://localhost:8080/imports/targets`;
+        const response = await fetch(url, options);
+        if (response.status !== 200) {
+            let message = await response.json();
+            return {
+                importSuccess: false,
+                message,
+            };
+        }
+        const importReport = await response.json();
+        return {
+            importSuccess: true,
+            importReport,
+        };
+    },
 };
\ No newline at end of file